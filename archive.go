@@ -0,0 +1,388 @@
+// archive.go
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Archives that can be listed like directories, by file name
+var archiveSuffixes = []string{
+	".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tbz", ".tar.xz", ".txz", ".zip", ".jar",
+}
+
+// isArchiveName reports whether a file name looks like a supported archive
+func isArchiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveFileInfo describes an entry inside an archive
+type archiveFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	owner   string
+	group   string
+	link    string // target of a symlink
+}
+
+func (a *archiveFileInfo) Name() string       { return a.name }
+func (a *archiveFileInfo) Size() int64        { return a.size }
+func (a *archiveFileInfo) Mode() os.FileMode  { return a.mode }
+func (a *archiveFileInfo) ModTime() time.Time { return a.modTime }
+func (a *archiveFileInfo) IsDir() bool        { return a.mode.IsDir() }
+func (a *archiveFileInfo) Sys() any           { return a }
+
+// archiveRootInfo describes an archive file as the directory it is listed as
+func archiveRootInfo(info os.FileInfo) os.FileInfo {
+	return &archiveFileInfo{
+		name:    info.Name(),
+		mode:    os.ModeDir | info.Mode().Perm(),
+		modTime: info.ModTime(),
+	}
+}
+
+// isListableArchive reports whether a file can be listed as a directory:
+// a regular file with an archive suffix whose index can be read. Anything
+// else, like a text file named fake.zip, is listed as a plain file.
+func isListableArchive(path string, info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || !isArchiveName(path) {
+		return false
+	}
+	_, err := openArchive(path, info)
+	return err == nil
+}
+
+// archiveOwner returns the owner and group recorded in an archive (tar
+// keeps user names, zip doesn't)
+func archiveOwner(file os.FileInfo) (string, string, bool) {
+	info, ok := file.Sys().(*archiveFileInfo)
+	if !ok || info.owner == "" {
+		return "", "", false
+	}
+	return info.owner, info.group, true
+}
+
+// A file or directory in an archive's index
+type archiveNode struct {
+	info     *archiveFileInfo
+	children map[string]*archiveNode
+}
+
+// Archives are indexed once, the first time something in them is listed
+var archiveCache = map[string]*archiveNode{}
+var archiveErrors = map[string]error{}
+
+// errNotInArchive is returned for paths that don't lead into an archive
+var errNotInArchive = errors.New("not inside an archive")
+
+// findArchiveNode looks up a path like "foo.tar.gz/inner/dir", where some
+// leading part of the path is an archive file
+func findArchiveNode(name string) (*archiveNode, error) {
+	name = filepath.ToSlash(name)
+	for i := len(name); i > 0; i = strings.LastIndex(name[:i], "/") {
+		archivePath := name[:i]
+		if !isArchiveName(archivePath) {
+			continue
+		}
+		info, err := os.Stat(archivePath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		root, err := openArchive(archivePath, info)
+		if err != nil {
+			return nil, err
+		}
+
+		// Walk down from the root, keeping the way back up for ".."
+		parents := []*archiveNode{root}
+		for _, part := range strings.Split(name[i:], "/") {
+			node := parents[len(parents)-1]
+			switch part {
+			case "", ".":
+				continue
+			case "..":
+				if len(parents) == 1 {
+					// Leaving the archive isn't handled here
+					return nil, errNotInArchive
+				}
+				parents = parents[:len(parents)-1]
+				continue
+			}
+			if !node.info.IsDir() {
+				return nil, syscall.ENOTDIR
+			}
+			child, ok := node.children[part]
+			if !ok {
+				return nil, syscall.ENOENT
+			}
+			parents = append(parents, child)
+		}
+		return parents[len(parents)-1], nil
+	}
+	return nil, errNotInArchive
+}
+
+// openArchive reads the index of an archive, or returns the cached one
+func openArchive(archivePath string, info os.FileInfo) (*archiveNode, error) {
+	if root, ok := archiveCache[archivePath]; ok {
+		return root, nil
+	}
+	if err, ok := archiveErrors[archivePath]; ok {
+		return nil, err
+	}
+
+	root := &archiveNode{
+		info:     archiveRootInfo(info).(*archiveFileInfo),
+		children: map[string]*archiveNode{},
+	}
+
+	var err error
+	lower := strings.ToLower(archivePath)
+	if strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".jar") {
+		err = readZipIndex(archivePath, root)
+	} else {
+		err = readTarIndex(archivePath, root)
+	}
+	if err != nil {
+		archiveErrors[archivePath] = err
+		return nil, err
+	}
+	archiveCache[archivePath] = root
+	return root, nil
+}
+
+// readZipIndex reads the central directory of a zip or jar file, without
+// touching the compressed data
+func readZipIndex(archivePath string, root *archiveNode) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		info := f.FileInfo()
+		root.add(f.Name, &archiveFileInfo{
+			size:    int64(f.UncompressedSize64),
+			mode:    info.Mode(),
+			modTime: f.Modified,
+		})
+	}
+	return nil
+}
+
+// readTarIndex reads the headers of a tar file, skipping over the data of
+// each entry. Compressed tar files are decompressed as a stream.
+func readTarIndex(archivePath string, root *archiveNode) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stream, err := decompress(archivePath, f)
+	if err != nil {
+		return err
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		mode := header.FileInfo().Mode()
+		if header.Typeflag == tar.TypeLink {
+			// Hard links are listed as the regular files they are
+			mode = mode.Perm()
+		}
+		root.add(header.Name, &archiveFileInfo{
+			size:    header.Size,
+			mode:    mode,
+			modTime: header.ModTime,
+			owner:   header.Uname,
+			group:   header.Gname,
+			link:    header.Linkname,
+		})
+	}
+	return nil
+}
+
+// decompress wraps a tar file in the decompressor its name calls for
+func decompress(archivePath string, f *os.File) (io.Reader, error) {
+	lower := strings.ToLower(archivePath)
+
+	switch {
+	case strings.HasSuffix(lower, ".gz"), strings.HasSuffix(lower, ".tgz"):
+		return gzip.NewReader(f)
+	case strings.HasSuffix(lower, ".bz2"), strings.HasSuffix(lower, ".tbz2"), strings.HasSuffix(lower, ".tbz"):
+		return bzip2.NewReader(f), nil
+	case strings.HasSuffix(lower, ".xz"), strings.HasSuffix(lower, ".txz"):
+		return xz.NewReader(bufio.NewReader(f))
+	}
+	return f, nil
+}
+
+// add puts an entry into the index under its path, creating the
+// directories above it that the archive doesn't list itself
+func (root *archiveNode) add(name string, info *archiveFileInfo) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return
+	}
+
+	node := root
+	parts := strings.Split(name, "/")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := node.children[part]
+		if !ok {
+			child = &archiveNode{
+				info: &archiveFileInfo{
+					name:    part,
+					mode:    os.ModeDir | 0755,
+					modTime: root.info.modTime,
+				},
+				children: map[string]*archiveNode{},
+			}
+			node.children[part] = child
+		}
+		node = child
+	}
+
+	last := parts[len(parts)-1]
+	info.name = last
+	if existing, ok := node.children[last]; ok && existing.info.IsDir() && info.IsDir() {
+		// A directory seen before its own header keeps its children
+		existing.info = info
+		return
+	}
+	node.children[last] = &archiveNode{info: info, children: map[string]*archiveNode{}}
+}
+
+// readDirPath is os.ReadDir that also reads archives and the directories
+// inside them, from the archive's index
+func readDirPath(dirPath string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err == nil {
+		return entries, nil
+	}
+	archiveEntries, archiveErr := readArchiveDir(dirPath)
+	if archiveErr == errNotInArchive {
+		return nil, err
+	}
+	return archiveEntries, archiveErr
+}
+
+// readArchiveDir lists a directory inside an archive, in name order like os.ReadDir
+func readArchiveDir(dirPath string) ([]os.DirEntry, error) {
+	node, err := findArchiveNode(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !node.info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: dirPath, Err: syscall.ENOTDIR}
+	}
+
+	entries := make([]os.DirEntry, 0, len(node.children))
+	for _, child := range node.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// lstatPath is os.Lstat that also finds entries inside archives
+func lstatPath(name string) (os.FileInfo, error) {
+	info, err := os.Lstat(name)
+	if err == nil {
+		return info, nil
+	}
+	node, archiveErr := findArchiveNode(name)
+	if archiveErr == nil {
+		return node.info, nil
+	}
+	// Inside an archive, its own error says more than os.Lstat's ENOTDIR
+	if archiveErr != errNotInArchive {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: archiveErr}
+	}
+	return nil, err
+}
+
+// statPath is os.Stat that also finds entries inside archives, following
+// symlinks there like the kernel does, up to the same 40 links
+func statPath(name string) (os.FileInfo, error) {
+	info, err := os.Stat(name)
+	base := filepath.Base(name)
+	for range 40 {
+		if err == nil {
+			return info, nil
+		}
+		node, archiveErr := findArchiveNode(name)
+		if archiveErr == errNotInArchive {
+			return nil, err
+		}
+		if archiveErr != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: archiveErr}
+		}
+		if node.info.mode&os.ModeSymlink == 0 {
+			// Named after the link, as os.Stat does
+			target := *node.info
+			target.name = base
+			return &target, nil
+		}
+
+		if filepath.IsAbs(node.info.link) {
+			name = node.info.link
+		} else {
+			name = filepath.Join(filepath.Dir(name), node.info.link)
+		}
+		info, err = os.Stat(name)
+	}
+	if err == nil {
+		return info, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: syscall.ELOOP}
+}
+
+// readLinkPath is os.Readlink that also reads symlinks inside archives
+func readLinkPath(name string) (string, error) {
+	target, err := os.Readlink(name)
+	if err == nil {
+		return target, nil
+	}
+	if node, archiveErr := findArchiveNode(name); archiveErr == nil && node.info.mode&os.ModeSymlink != 0 {
+		return node.info.link, nil
+	}
+	return "", err
+}
@@ -0,0 +1,28 @@
+// birth_time_linux.go
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// getBirthTime returns when a file was created, which only statx reports
+// and only on filesystems that record it
+func getBirthTime(file os.FileInfo) (time.Time, bool) {
+	// Entries that are symlinks describe the link itself, others may
+	// have been followed to get here
+	flags := 0
+	if file.Mode()&os.ModeSymlink != 0 {
+		flags = unix.AT_SYMLINK_NOFOLLOW
+	}
+
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, entryPath(file), flags, unix.STATX_BTIME, &stx)
+	if err != nil || stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
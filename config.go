@@ -0,0 +1,442 @@
+// config.go
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Icons and colors set in the config file, on top of the built-in ones
+var (
+	fileNameIcons  = map[string]string{} // [icons.files], by exact file name
+	directoryIcons = map[string]string{} // [icons.directories], by directory name
+	typeColors     = map[string]string{} // [colors], by file type or extension
+)
+
+// a key = value line of the config file, remembered with its position
+type configEntry struct {
+	section string
+	key     string
+	value   any // string, bool or int
+	line    int
+}
+
+// getConfigPath returns the default config file location,
+// $XDG_CONFIG_HOME/lsx/config.toml or ~/.config/lsx/config.toml
+func getConfigPath() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "lsx", "config.toml")
+}
+
+// findConfigFlag looks for --config before the flags are parsed,
+// since the config file provides their defaults
+func findConfigFlag(args []string) (string, bool) {
+	for i := 1; i < len(args); i++ {
+		if value, ok := strings.CutPrefix(args[i], "--config="); ok {
+			return value, true
+		}
+		if args[i] == "--config" && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// loadConfig reads the config file into the icon and color maps and
+// applies its [defaults] to flags. A missing default config is not an error.
+func loadConfig(args []string, flags *Flags) error {
+	path, explicit := findConfigFlag(args)
+	if !explicit {
+		path = getConfigPath()
+	}
+	if path == "" {
+		return nil
+	}
+
+	entries, err := parseConfigFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := applyConfigEntry(entry, flags); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, entry.line, err)
+		}
+	}
+	return nil
+}
+
+// stores one config entry in the place its section points to
+func applyConfigEntry(entry configEntry, flags *Flags) error {
+	switch entry.section {
+	case "defaults":
+		return applyConfigDefault(entry.key, entry.value, flags)
+
+	case "icons.extensions", "icons.files", "icons.directories":
+		icon, ok := entry.value.(string)
+		if !ok || icon == "" {
+			return fmt.Errorf("icon for '%s' must be a non-empty string", entry.key)
+		}
+		switch entry.section {
+		case "icons.extensions":
+			Icons[normalizeExtension(entry.key)] = icon
+		case "icons.files":
+			fileNameIcons[entry.key] = icon
+		case "icons.directories":
+			directoryIcons[entry.key] = icon
+		}
+
+	case "colors":
+		value, ok := entry.value.(string)
+		if !ok {
+			return fmt.Errorf("color for '%s' must be a string", entry.key)
+		}
+		color, err := parseColorValue(value)
+		if err != nil {
+			return err
+		}
+		key := entry.key
+		if !isFileTypeKey(key) {
+			key = normalizeExtension(key)
+		}
+		typeColors[key] = color
+
+	default:
+		return fmt.Errorf("unknown section [%s]", entry.section)
+	}
+	return nil
+}
+
+// sets one flag from the [defaults] section
+func applyConfigDefault(key string, value any, flags *Flags) error {
+	switch key {
+	case "long", "all", "almost_all", "human_readable", "recursive", "tree", "reverse",
+		"group_directories_first", "one_per_line", "across", "git", "gitignore", "total_size",
+		"si", "size", "inode", "detect":
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("'%s' must be true or false", key)
+		}
+		switch key {
+		case "long":
+			flags.LongFormat = enabled
+		case "all":
+			flags.AllFiles = enabled
+		case "almost_all":
+			flags.AlmostAll = enabled
+		case "human_readable":
+			flags.HumanReadable = enabled
+		case "recursive":
+			flags.Recursive = enabled
+		case "tree":
+			flags.Tree = enabled
+		case "reverse":
+			flags.Reverse = enabled
+		case "group_directories_first":
+			flags.GroupDirsFirst = enabled
+		case "one_per_line":
+			flags.OnePerLine = enabled
+		case "across":
+			flags.Across = enabled
+		case "git":
+			flags.Git = enabled
+		case "gitignore":
+			flags.GitIgnore = enabled
+		case "total_size":
+			flags.TotalSize = enabled
+		case "si":
+			flags.SI = enabled
+		case "size":
+			flags.ShowBlocks = enabled
+		case "inode":
+			flags.Inode = enabled
+		case "detect":
+			flags.Detect = enabled
+		}
+
+	case "level", "width", "icon_width":
+		number, ok := value.(int)
+		if !ok || number < 1 {
+			return fmt.Errorf("'%s' must be a positive number", key)
+		}
+		switch key {
+		case "level":
+			flags.TreeLevel = number
+		case "width":
+			flags.Width = number
+		case "icon_width":
+			if number > 2 {
+				return fmt.Errorf("'icon_width' must be 1 or 2")
+			}
+			flags.IconWidth = number
+		}
+
+	case "columns":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("'columns' must be a string like \"perms,size,name\"")
+		}
+		columns, err := parseColumns(text)
+		if err != nil {
+			return err
+		}
+		flags.Columns = columns
+
+	case "time", "time_style":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("'%s' must be a string", key)
+		}
+		if key == "time_style" {
+			if !validTimeStyle(text) {
+				return fmt.Errorf("invalid time style '%s'", text)
+			}
+			flags.TimeStyle = text
+			return nil
+		}
+		field, ok := parseTimeField(text)
+		if !ok {
+			return fmt.Errorf("invalid time '%s'", text)
+		}
+		flags.TimeField = field
+
+	case "sort", "dircolors", "color", "icons":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("'%s' must be a string", key)
+		}
+		switch key {
+		case "dircolors":
+			flags.DircolorsFile = text
+			return nil
+		case "color", "icons":
+			if text != ModeAuto && text != ModeAlways && text != ModeNever {
+				return fmt.Errorf("'%s' must be auto, always or never", key)
+			}
+			if key == "color" {
+				flags.ColorMode = text
+			} else {
+				flags.IconsMode = text
+			}
+			return nil
+		}
+		switch text {
+		case SortName, SortNone, SortSize, SortTime, SortExtension, SortVersion:
+			flags.SortBy = text
+		default:
+			return fmt.Errorf("invalid sort '%s'", text)
+		}
+
+	default:
+		return fmt.Errorf("unknown setting '%s' in [defaults]", key)
+	}
+	return nil
+}
+
+// File types that can be given a color in [colors]
+var fileTypeKeys = []string{"directory", "symlink", "pipe", "socket", "device", "executable", "default"}
+
+func isFileTypeKey(key string) bool {
+	return isInList(key, fileTypeKeys)
+}
+
+// makes "go" and ".GO" both mean ".go", the form the Icons map uses
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// parseColorValue turns a color setting into an escape sequence. It accepts
+// names from the Color map ("bold bright_blue") or a raw SGR code ("01;34").
+func parseColorValue(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("empty color")
+	}
+
+	// Raw SGR codes contain only digits and semicolons
+	if strings.Trim(value, "0123456789;") == "" {
+		return "\x1b[" + value + "m", nil
+	}
+
+	color := ""
+	for _, name := range strings.Fields(value) {
+		code, ok := Color[name]
+		if !ok {
+			return "", fmt.Errorf("unknown color '%s'", name)
+		}
+		color += code
+	}
+	return color, nil
+}
+
+// getConfigColor returns the color the config file sets for an entry, if any
+func getConfigColor(file os.FileInfo) (string, bool) {
+	if len(typeColors) == 0 {
+		return "", false
+	}
+
+	mode := file.Mode()
+	switch {
+	case mode.IsDir():
+		return lookupColor("directory")
+	case mode&os.ModeSymlink != 0:
+		return lookupColor("symlink")
+	case mode&os.ModeNamedPipe != 0:
+		return lookupColor("pipe")
+	case mode&os.ModeSocket != 0:
+		return lookupColor("socket")
+	case mode&os.ModeDevice != 0:
+		return lookupColor("device")
+	case mode&0111 != 0:
+		if color, ok := lookupColor("executable"); ok {
+			return color, true
+		}
+	}
+
+	if color, ok := lookupColor(strings.ToLower(filepath.Ext(file.Name()))); ok {
+		return color, true
+	}
+	return lookupColor("default")
+}
+
+func lookupColor(key string) (string, bool) {
+	color, ok := typeColors[key]
+	return color, ok
+}
+
+// parseConfigFile reads the small subset of TOML the config file uses:
+// [section] headers and key = value lines with strings, booleans and integers
+func parseConfigFile(path string) ([]configEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []configEntry
+	section := ""
+	lineNum := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		// Section header
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%s:%d: unterminated section header", path, lineNum)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		rawKey, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, lineNum)
+		}
+
+		key, err := parseConfigKey(strings.TrimSpace(rawKey))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		value, err := parseConfigValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+
+		entries = append(entries, configEntry{section: section, key: key, value: value, line: lineNum})
+	}
+	return entries, scanner.Err()
+}
+
+// removes a trailing # comment, leaving # inside quoted strings alone
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote == '"':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parses a bare or quoted key
+func parseConfigKey(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("missing key")
+	}
+	if key[0] == '"' || key[0] == '\'' {
+		value, err := parseConfigValue(key)
+		if err != nil {
+			return "", err
+		}
+		text, _ := value.(string)
+		return text, nil
+	}
+	return key, nil
+}
+
+// parses a string, boolean or integer value
+func parseConfigValue(value string) (any, error) {
+	switch {
+	case value == "":
+		return nil, fmt.Errorf("missing value")
+	case value == "true":
+		return true, nil
+	case value == "false":
+		return false, nil
+	case value[0] == '"':
+		// Basic strings use the same escapes as Go, like ""
+		text, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", value)
+		}
+		return text, nil
+	case value[0] == '\'':
+		// Literal strings have no escapes
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("invalid string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", value)
+	}
+	return number, nil
+}
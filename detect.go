@@ -0,0 +1,190 @@
+// detect.go
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Whether --detect sniffs file contents to pick icons and colors
+var detectEnabled = false
+
+// A file type recognized from its first bytes
+type detectedType struct {
+	colorKey    string
+	iconKey     string
+	description string // shown in the "type" column
+}
+
+// Types recognized by their magic bytes
+var (
+	typeELF    = &detectedType{"green", "executable", "ELF binary"}
+	typePE     = &detectedType{"green", "executable", "PE binary"}
+	typeMachO  = &detectedType{"green", "executable", "Mach-O binary"}
+	typePNG    = &detectedType{getColorForFileType(".png"), ".png", "PNG image"}
+	typeJPEG   = &detectedType{getColorForFileType(".jpg"), ".jpg", "JPEG image"}
+	typePDF    = &detectedType{getColorForFileType(".pdf"), ".pdf", "PDF document"}
+	typeGzip   = &detectedType{getColorForFileType(".gz"), ".gz", "gzip archive"}
+	typeZip    = &detectedType{getColorForFileType(".zip"), ".zip", "zip archive"}
+	typeScript = &detectedType{"green", "executable", "script"}
+)
+
+// Script interpreters named on shebang lines, and the extension whose
+// icon and color their scripts get
+var interpreterExtensions = map[string]string{
+	"sh": ".sh", "bash": ".sh", "dash": ".sh", "zsh": ".sh", "ksh": ".sh", "fish": ".sh",
+	"python": ".py", "pypy": ".py",
+	"node": ".js", "nodejs": ".js", "deno": ".ts", "bun": ".js",
+	"ruby": ".rb",
+	"perl": ".pl",
+	"php":  ".php",
+	"lua":  ".lua",
+}
+
+// How many bytes of a file are read to detect its type
+const sniffLength = 512
+
+// Files are sniffed once per inode and modification time
+type detectKey struct {
+	id    fileID
+	path  string // used where there is no inode
+	mtime int64
+}
+
+var detectCache = map[detectKey]*detectedType{}
+
+// detectFileType sniffs the type of a regular file from its contents,
+// returning nil when it isn't one of the known types or can't be read
+func detectFileType(file os.FileInfo) *detectedType {
+	if !file.Mode().IsRegular() || file.Size() == 0 {
+		return nil
+	}
+
+	key := detectKey{mtime: file.ModTime().UnixNano()}
+	if id, ok := getFileID(file); ok {
+		key.id = id
+	} else {
+		key.path = entryPath(file)
+	}
+	if detected, ok := detectCache[key]; ok {
+		return detected
+	}
+
+	detected := sniffFile(entryPath(file))
+	detectCache[key] = detected
+	return detected
+}
+
+// sniffFile reads the start of a file and matches it against the known
+// magic numbers
+func sniffFile(path string) *detectedType {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil
+	}
+	return matchMagic(head[:n], f)
+}
+
+// matchMagic identifies a file from its first bytes, reading further
+// from f where a format keeps its signature elsewhere
+func matchMagic(head []byte, f io.ReaderAt) *detectedType {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return typeELF
+	case isPE(head, f):
+		return typePE
+	case isMachO(head):
+		return typeMachO
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return typePNG
+	case bytes.HasPrefix(head, []byte{0xff, 0xd8, 0xff}):
+		return typeJPEG
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return typePDF
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return typeGzip
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return typeZip
+	case bytes.HasPrefix(head, []byte("#!")):
+		return detectScript(head)
+	}
+	return nil
+}
+
+// isPE checks for a DOS header whose e_lfanew field, at 0x3c, points to
+// the "PE\0\0" signature, so text that happens to start with "MZ" isn't taken
+// for a binary
+func isPE(head []byte, f io.ReaderAt) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+
+	offset := int64(binary.LittleEndian.Uint32(head[0x3c:]))
+	signature := make([]byte, 4)
+	if offset+4 <= int64(len(head)) {
+		copy(signature, head[offset:])
+	} else if _, err := f.ReadAt(signature, offset); err != nil {
+		return false
+	}
+	return string(signature) == "PE\x00\x00"
+}
+
+// isMachO checks for the 32 and 64 bit Mach-O magic numbers in either
+// byte order, and for universal binaries
+func isMachO(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(head) {
+	case 0xfeedface, 0xcefaedfe, 0xfeedfacf, 0xcffaedfe:
+		return true
+	case 0xcafebabe:
+		// Java class files share this magic; their version number that
+		// follows is far larger than the architecture count of a universal binary
+		return binary.BigEndian.Uint32(head[4:]) < 45
+	}
+	return false
+}
+
+// detectScript reads the interpreter off a shebang line, going past
+// "env" and its options, and strips version numbers like "python3.12"
+func detectScript(head []byte) *detectedType {
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return typeScript
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	interpreter = strings.TrimRightFunc(interpreter, func(r rune) bool {
+		return unicode.IsDigit(r) || r == '.'
+	})
+
+	ext, ok := interpreterExtensions[interpreter]
+	if !ok {
+		return typeScript
+	}
+	return &detectedType{getColorForFileType(ext), ext, interpreter + " script"}
+}
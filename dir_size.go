@@ -0,0 +1,202 @@
+// dir_size.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Number of workers reading directories while measuring sizes
+var sizeWorkers = min(runtime.NumCPU()*2, 16)
+
+// sizeTotal is what everything below a directory adds up to
+type sizeTotal struct {
+	apparent int64
+	disk     int64
+}
+
+// Totals of every directory measured so far, by cleaned path, so -R and
+// --tree don't walk a subtree again for each directory above it
+var dirTotals = map[string]sizeTotal{}
+
+// Hard linked files already counted. Like du, a file is only counted
+// in the first directory it's found in during the run.
+var sizeSeen = map[fileID]bool{}
+
+// sizeDir is one directory being measured. Its own entries are added up
+// by the worker that reads it, subdirectories are added in once all
+// workers are done.
+type sizeDir struct {
+	path     string
+	apparent int64 // the directory itself and the files directly inside
+	disk     int64
+	children []*sizeDir
+}
+
+// sizePool is a fixed set of workers sharing a queue of directories.
+// Reading a directory queues its subdirectories, and the workers stop
+// once the queue is empty and no directory is still being read.
+type sizePool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*sizeDir
+	pending int      // directories queued or being read
+	errors  []string // reported once all workers are done
+}
+
+// applyTotalSizes replaces the size of every directory in a listing with
+// the total of everything below it, when --total-size is given
+func applyTotalSizes(files []os.FileInfo, flags Flags) {
+	if !flags.TotalSize {
+		return
+	}
+
+	pool := &sizePool{}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	roots := map[*fileEntry]*sizeDir{}
+	for _, file := range files {
+		entry, ok := file.(*fileEntry)
+		if !ok || !entry.IsDir() || entry.name == ".." {
+			continue
+		}
+		if total, ok := dirTotals[filepath.Clean(entry.path)]; ok {
+			entry.totalSize, entry.totalDisk = total.apparent, total.disk
+			entry.hasTotal = true
+			continue
+		}
+
+		dir := &sizeDir{path: entry.path}
+		pool.add(dir, entry.FileInfo)
+		roots[entry] = dir
+		pool.push(dir)
+	}
+	if len(roots) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for range sizeWorkers {
+		wg.Add(1)
+		go pool.work(&wg)
+	}
+	wg.Wait()
+
+	for entry, dir := range roots {
+		total := dir.sum()
+		entry.totalSize, entry.totalDisk = total.apparent, total.disk
+		entry.hasTotal = true
+	}
+
+	// Errors come from many workers, so they are printed here in one go
+	sort.Strings(pool.errors)
+	for _, message := range pool.errors {
+		reportError(exitMinor, "%s", message)
+	}
+}
+
+// sum adds up a measured directory bottom up, remembering the total of
+// every directory on the way
+func (d *sizeDir) sum() sizeTotal {
+	total := sizeTotal{d.apparent, d.disk}
+	for _, child := range d.children {
+		childTotal := child.sum()
+		total.apparent += childTotal.apparent
+		total.disk += childTotal.disk
+	}
+	dirTotals[filepath.Clean(d.path)] = total
+	return total
+}
+
+// queues a directory to be read
+func (p *sizePool) push(dir *sizeDir) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queue = append(p.queue, dir)
+	p.pending++
+	p.cond.Signal()
+}
+
+// work reads queued directories until there are none left anywhere
+func (p *sizePool) work(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && p.pending > 0 {
+			p.cond.Wait()
+		}
+		if p.pending == 0 {
+			p.mu.Unlock()
+			return
+		}
+
+		// Taking the newest job walks depth first, keeping the queue short
+		dir := p.queue[len(p.queue)-1]
+		p.queue = p.queue[:len(p.queue)-1]
+		p.mu.Unlock()
+
+		p.read(dir)
+
+		p.mu.Lock()
+		p.pending--
+		if p.pending == 0 {
+			// Wake the idle workers so they can stop
+			p.cond.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// reads one directory, counting its files and queueing its subdirectories
+func (p *sizePool) read(dir *sizeDir) {
+	// Directories inside an archive are walked through its index
+	entries, err := readDirPath(dir.path)
+	if err != nil {
+		p.mu.Lock()
+		p.errors = append(p.errors, "cannot read directory '"+dir.path+"': "+describeError(err))
+		p.mu.Unlock()
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		// Symlinks aren't followed, so the walk can't loop
+		if entry.IsDir() {
+			child := &sizeDir{path: joinPath(dir.path, entry.Name())}
+			p.add(child, info)
+			dir.children = append(dir.children, child)
+			p.push(child)
+			continue
+		}
+		p.add(dir, info)
+	}
+}
+
+// counts one entry towards a directory, skipping hard links that were
+// already counted
+func (p *sizePool) add(dir *sizeDir, info os.FileInfo) {
+	if !info.IsDir() && getLinkCount(info) > 1 {
+		if id, ok := getFileID(info); ok {
+			p.mu.Lock()
+			seen := sizeSeen[id]
+			sizeSeen[id] = true
+			p.mu.Unlock()
+			if seen {
+				return
+			}
+		}
+	}
+
+	dir.apparent += info.Size()
+	dir.disk += getAllocatedSize(info)
+}
@@ -0,0 +1,148 @@
+// display_width.go
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Number of terminal cells a Nerd Font icon takes, set with --icon-width.
+// Most terminals draw them in one cell, some stretch them over two.
+var iconWidth = 1
+
+// a closed range of code points
+type runeRange struct {
+	first rune
+	last  rune
+}
+
+// Code points drawn two cells wide: East Asian Wide and Fullwidth
+// characters and emoji presentation symbols, sorted by first
+var wideRanges = []runeRange{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18CFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F7E0, 0x1F7EB}, {0x1F90C, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// Private use areas, where Nerd Font puts its icons
+var privateUseRanges = []runeRange{
+	{0xE000, 0xF8FF}, {0xF0000, 0xFFFFD}, {0x100000, 0x10FFFD},
+}
+
+const (
+	zeroWidthJoiner   = '\u200d'
+	emojiPresentation = '\ufe0f' // variation selector 16
+)
+
+// displayWidth returns how many terminal cells a string takes up
+func displayWidth(s string) int {
+	width := 0
+	prevWidth := 0
+	joined := false
+
+	for _, r := range s {
+		if r == emojiPresentation && prevWidth == 1 {
+			// A narrow symbol followed by VS16 is drawn as a wide emoji
+			width++
+			prevWidth = 2
+			continue
+		}
+
+		w := runeWidth(r)
+		if joined && w > 0 {
+			// The emoji after a ZWJ merges into the previous glyph
+			w = 0
+		}
+		joined = r == zeroWidthJoiner
+
+		if w > 0 {
+			prevWidth = w
+		}
+		width += w
+	}
+	return width
+}
+
+// runeWidth returns the number of cells a single rune takes up
+func runeWidth(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case r < 0x20 || (r >= 0x7F && r < 0xA0):
+		// Control characters
+		return 0
+	case r < 0x300:
+		// Fast path for Latin text
+		return 1
+	case isZeroWidth(r):
+		return 0
+	case inRanges(r, privateUseRanges):
+		return iconWidth
+	case inRanges(r, wideRanges):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// reports whether a rune is drawn on top of the previous one
+func isZeroWidth(r rune) bool {
+	if r >= 0x1160 && r <= 0x11FF {
+		// Hangul Jamo medial vowels and final consonants
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf)
+}
+
+// reports whether a rune falls in one of the sorted ranges
+func inRanges(r rune, ranges []runeRange) bool {
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].last >= r
+	})
+	return i < len(ranges) && ranges[i].first <= r
+}
+
+// visibleWidth is displayWidth for text that may hold color escape sequences
+func visibleWidth(s string) int {
+	var plain strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			// Skip to the final byte of the sequence, like the "m" of a color
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			continue
+		}
+		plain.WriteByte(s[i])
+	}
+	return displayWidth(plain.String())
+}
+
+// truncateToWidth cuts plain text down to at most width cells
+func truncateToWidth(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		w := runeWidth(r)
+		if used+w > width {
+			return s[:i]
+		}
+		used += w
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+// entry.go
+
+package main
+
+import "os"
+
+// fileEntry is an os.FileInfo that also remembers where the file was found,
+// for the features that need to look past the lstat data (symlink targets,
+// colors for broken links and so on)
+type fileEntry struct {
+	os.FileInfo
+	path  string
+	label string // shown instead of the name, for paths given as arguments
+	name  string // replaces the base name, for the . and .. entries
+
+	// Recursive totals of a directory, set by --total-size
+	hasTotal  bool
+	totalSize int64 // apparent size in bytes
+	totalDisk int64 // allocated bytes on disk
+}
+
+// newFileEntry wraps file info read from the given path
+func newFileEntry(info os.FileInfo, path string) os.FileInfo {
+	return &fileEntry{FileInfo: info, path: path}
+}
+
+// newNamedEntry wraps file info under a different name, like "." for the
+// directory being listed
+func newNamedEntry(info os.FileInfo, path, name string) os.FileInfo {
+	return &fileEntry{FileInfo: info, path: path, name: name}
+}
+
+// Name returns the entry's name, which differs from the file's own
+// base name for . and ..
+func (e *fileEntry) Name() string {
+	if e.name != "" {
+		return e.name
+	}
+	return e.FileInfo.Name()
+}
+
+// Size returns the recursive total for directories measured by --total-size,
+// and the file's own size otherwise
+func (e *fileEntry) Size() int64 {
+	if e.hasTotal {
+		return e.totalSize
+	}
+	return e.FileInfo.Size()
+}
+
+// newArgumentEntry wraps file info for a path named on the command line,
+// which is displayed as written rather than by its base name
+func newArgumentEntry(info os.FileInfo, path string) os.FileInfo {
+	return &fileEntry{FileInfo: info, path: path, label: path}
+}
+
+// entryPath returns the path an entry was read from, or just its name
+// if it wasn't read through newFileEntry
+func entryPath(file os.FileInfo) string {
+	if entry, ok := file.(*fileEntry); ok {
+		return entry.path
+	}
+	return file.Name()
+}
+
+// entryLabel returns the text to display for an entry
+func entryLabel(file os.FileInfo) string {
+	if entry, ok := file.(*fileEntry); ok && entry.label != "" {
+		return entry.label
+	}
+	return file.Name()
+}
+
+// entryDiskUsage returns the bytes an entry takes on disk, including
+// everything below a directory measured by --total-size
+func entryDiskUsage(file os.FileInfo) int64 {
+	if entry, ok := file.(*fileEntry); ok && entry.hasTotal {
+		return entry.totalDisk
+	}
+	return getAllocatedSize(file)
+}
@@ -5,13 +5,6 @@ import (
 	"strings"
 )
 
-// getFileOwner returns the owner and group of a file
-func getFileOwner(filePath string) (string, string) {
-	// In Windows, for simplicity, just use the current user as owner & group.
-	username := getCurrentUsername()
-	return username, username
-}
-
 // getCurrentUsername gets the current user's name
 func getCurrentUsername() string {
 	currentUser, err := user.Current()
@@ -5,7 +5,8 @@ package main
 import (
 	"fmt"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -15,24 +16,77 @@ const (
 	TB = 1024 * GB
 )
 
-// Format file size as a string, & in human-readable form.
-func FormatFileSize(size int64, humanReadable bool) string {
-	if !humanReadable {
+// Format file size as a string: human-readable with -h (powers of 1024)
+// or --si (powers of 1000), in --block-size units if given, or in bytes.
+func FormatFileSize(size int64, flags Flags) string {
+	switch {
+	case flags.HumanReadable:
+		return formatHumanSize(size, KB, "KMGT")
+	case flags.SI:
+		return formatHumanSize(size, 1000, "kMGT")
+	case flags.BlockSize > 1:
+		return strconv.FormatInt(ceilDiv(size, flags.BlockSize), 10) + flags.BlockSuffix
+	default:
 		return fmt.Sprintf("%d", size)
 	}
+}
 
-	switch {
-	case size >= TB:
-		return fmt.Sprintf("%.1fT", float64(size)/float64(TB))
-	case size >= GB:
-		return fmt.Sprintf("%.1fG", float64(size)/float64(GB))
-	case size >= MB:
-		return fmt.Sprintf("%.1fM", float64(size)/float64(MB))
-	case size >= KB:
-		return fmt.Sprintf("%.1fK", float64(size)/float64(KB))
-	default:
-		return fmt.Sprintf("%dB", size)
+// formats a size in the largest unit that keeps it below base, units
+// holding the letters for base^1 to base^4. Like coreutils it rounds up,
+// shows one decimal below 10 and none from 10 on.
+func formatHumanSize(size int64, base int64, units string) string {
+	if size < base {
+		return strconv.FormatInt(size, 10)
+	}
+
+	unit := int64(1)
+	for i := 0; i < len(units); i++ {
+		unit *= base
+		if tenths := ceilDiv(size*10, unit); tenths < 100 {
+			return fmt.Sprintf("%d.%d%c", tenths/10, tenths%10, units[i])
+		}
+		// Rounding up may reach the next unit, 1023.5K is shown as 1.0M
+		if whole := ceilDiv(size, unit); whole < base || i == len(units)-1 {
+			return fmt.Sprintf("%d%c", whole, units[i])
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// FormatBlocks formats an allocated size the way the "total" line and -s
+// show it: in 1K blocks (or --block-size units), or human-readable
+func FormatBlocks(bytes int64, flags Flags) string {
+	if flags.HumanReadable || flags.SI {
+		return FormatFileSize(bytes, flags)
+	}
+
+	unit := int64(KB)
+	if flags.BlockSize > 0 {
+		unit = flags.BlockSize
+	}
+	return strconv.FormatInt(ceilDiv(bytes, unit), 10) + flags.BlockSuffix
+}
+
+// divides rounding up, since a partly used block still counts
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// parseBlockSize reads a --block-size value. Like ls, a unit without a
+// number ("K", "MB") is shown as a suffix on every size, "1K" or "4096" isn't.
+func parseBlockSize(value string) (int64, string, error) {
+	suffix := ""
+	if value != "" && (value[0] < '0' || value[0] > '9') {
+		// A bare unit like "K" means one of that unit
+		suffix = value
+		value = "1" + value
 	}
+
+	size, err := parseSize(value)
+	if err != nil || size < 1 {
+		return 0, "", fmt.Errorf("invalid block size %q", value)
+	}
+	return size, suffix, nil
 }
 
 // Converts file mode to a Unix-like permission string.
@@ -41,28 +95,39 @@ func FormatPermissions(info os.FileInfo) string {
 	perms := ""
 
 	// File type
-	if mode.IsDir() {
+	switch {
+	case mode.IsDir():
 		perms += "d"
-	} else if mode&os.ModeSymlink != 0 {
+	case mode&os.ModeSymlink != 0:
 		perms += "l"
-	} else {
+	case mode&os.ModeNamedPipe != 0:
+		perms += "p"
+	case mode&os.ModeSocket != 0:
+		perms += "s"
+	case mode&os.ModeCharDevice != 0:
+		perms += "c"
+	case mode&os.ModeDevice != 0:
+		perms += "b"
+	default:
 		perms += "-"
 	}
 
-	// User permissions
-	perms += formatPermissionBits(mode, 0400, 0200, 0100)
+	// User permissions, with the setuid bit in the execute slot
+	perms += formatPermissionBits(mode, 0400, 0200, 0100, mode&os.ModeSetuid != 0, 's')
 
-	// Group permissions
-	perms += formatPermissionBits(mode, 040, 020, 010)
+	// Group permissions, with the setgid bit in the execute slot
+	perms += formatPermissionBits(mode, 040, 020, 010, mode&os.ModeSetgid != 0, 's')
 
-	// Other permissions
-	perms += formatPermissionBits(mode, 04, 02, 01)
+	// Other permissions, with the sticky bit in the execute slot
+	perms += formatPermissionBits(mode, 04, 02, 01, mode&os.ModeSticky != 0, 't')
 
 	return perms
 }
 
 // convert permission to rwx notation for user/group/others.
-func formatPermissionBits(mode os.FileMode, r, w, x os.FileMode) string {
+// When special is set, the execute slot shows the special character instead
+// (lowercase if executable, uppercase if not), as ls does.
+func formatPermissionBits(mode os.FileMode, r, w, x os.FileMode, special bool, specialChar rune) string {
 	result := ""
 	if mode&r != 0 {
 		result += "r"
@@ -76,20 +141,16 @@ func formatPermissionBits(mode os.FileMode, r, w, x os.FileMode) string {
 		result += "-"
 	}
 
-	if mode&x != 0 {
+	switch {
+	case special && mode&x != 0:
+		result += string(specialChar)
+	case special:
+		result += strings.ToUpper(string(specialChar))
+	case mode&x != 0:
 		result += "x"
-	} else {
+	default:
 		result += "-"
 	}
 
 	return result
 }
-
-// formats the modification time of a file
-func FormatModTime(modTime time.Time) string {
-	// Recent files show time, older files show year
-	if time.Since(modTime) > 6*30*24*time.Hour { // Older than ~6 months
-		return modTime.Format("Jan _2  2006")
-	}
-	return modTime.Format("Jan _2 15:04")
-}
@@ -0,0 +1,133 @@
+// filters.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isPruned reports whether an entry is left out of a directory listing
+// entirely, so -R and --tree don't descend into it either
+func isPruned(name, path string, isDir bool, flags Flags) bool {
+	// Skip dotfiles - unless -a or -A flag is set
+	hidden := strings.HasPrefix(name, ".")
+	if hidden && !flags.AllFiles && !flags.AlmostAll {
+		return true
+	}
+
+	for _, pattern := range flags.IgnorePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	// --hide is like -I, but -a and -A show everything again
+	if !flags.AllFiles && !flags.AlmostAll {
+		for _, pattern := range flags.HidePatterns {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	// Skip entries the repository's ignore files exclude - with --gitignore
+	return flags.GitIgnore && isGitIgnored(path, isDir)
+}
+
+// filterEntries applies the filters that only decide what is shown:
+// --only-dirs, --only-files and the size and age ranges. Size and age
+// only apply to files, since a directory's own size and time say little
+// about its contents.
+func filterEntries(files []os.FileInfo, flags Flags) []os.FileInfo {
+	filtered := make([]os.FileInfo, 0, len(files))
+	for _, file := range files {
+		if passesFilters(file, flags) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// passesFilters reports whether an entry is shown by filterEntries
+func passesFilters(file os.FileInfo, flags Flags) bool {
+	if file.IsDir() {
+		return !flags.OnlyFiles
+	}
+	if flags.OnlyDirs {
+		return false
+	}
+
+	if flags.LargerThan >= 0 && file.Size() <= flags.LargerThan {
+		return false
+	}
+	if flags.SmallerThan >= 0 && file.Size() >= flags.SmallerThan {
+		return false
+	}
+
+	age := time.Since(file.ModTime())
+	if flags.NewerThan > 0 && age > flags.NewerThan {
+		return false
+	}
+	if flags.OlderThan > 0 && age < flags.OlderThan {
+		return false
+	}
+	return true
+}
+
+// parseSize reads a size like "512", "10K", "1.5M" or "2GiB". Single
+// letters and "iB" suffixes are powers of 1024, "KB", "MB"... are powers of 1000.
+func parseSize(value string) (int64, error) {
+	number := strings.TrimRight(value, "KMGTPEkmgtpeiBb")
+	suffix := strings.ToUpper(value[len(number):])
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	multiplier := float64(1)
+	if suffix != "" && suffix != "B" {
+		power := strings.IndexByte("KMGTPE", suffix[0]) + 1
+		if power == 0 {
+			return 0, fmt.Errorf("invalid size %q", value)
+		}
+
+		base := float64(1024)
+		switch suffix[1:] {
+		case "", "IB":
+		case "B":
+			base = 1000
+		default:
+			return 0, fmt.Errorf("invalid size %q", value)
+		}
+		for ; power > 0; power-- {
+			multiplier *= base
+		}
+	}
+	return int64(amount * multiplier), nil
+}
+
+// parseAge reads an age like "30s", "15m", "2h", "2d" or "1w",
+// or anything time.ParseDuration accepts
+func parseAge(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	for unit, length := range units {
+		if number, ok := strings.CutSuffix(value, unit); ok {
+			amount, err := strconv.ParseFloat(number, 64)
+			if err != nil || amount < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(amount * float64(length)), nil
+		}
+	}
+	return time.ParseDuration(value)
+}
@@ -3,23 +3,84 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Flags structure to hold command line flags
 type Flags struct {
-	LongFormat    bool // -l flag
-	AllFiles      bool // -a flag
-	DirectoryOnly bool // -d or --directory flag
-	HumanReadable bool // -h or --human-readable flag
-	Help          bool // --help flag
+	LongFormat    bool   // -l flag
+	AllFiles      bool   // -a or --all flag, also lists . and ..
+	AlmostAll     bool   // -A or --almost-all flag
+	DirectoryOnly bool   // -d or --directory flag
+	HumanReadable bool   // -h or --human-readable flag
+	SI            bool   // --si flag, like -h but in powers of 1000
+	BlockSize     int64  // --block-size=SIZE flag, 0 means bytes for sizes and 1K for blocks
+	BlockSuffix   string // unit shown after sizes when --block-size was a bare unit like "M"
+	ShowBlocks    bool   // -s or --size flag
+	Inode         bool   // -i or --inode flag
+	Detect        bool   // --detect flag
+	Context       bool   // -Z or --context flag
+	Xattrs        bool   // -@ flag
+	Interactive   bool   // --interactive flag, the full-screen browser
+	TotalSize     bool   // --total-size flag
+	Recursive     bool   // -R or --recursive flag
+	Classify      bool   // -F or --classify flag
+	Dereference   bool   // -L or --dereference flag
+	DerefArgs     bool   // -H or --dereference-command-line flag
+	Git           bool   // --git flag
+	GitIgnore     bool   // --gitignore flag
+	Tree          bool   // --tree flag
+	TreeLevel     int    // --level=N flag, 0 means no depth limit
+	Help          bool   // --help flag
 
-	Version bool // -v or --version flag
+	Version bool // --version flag
+
+	// Filters
+	IgnorePatterns []string      // -I PATTERN or --ignore=PATTERN flags
+	HidePatterns   []string      // --hide=PATTERN flags, overridden by -a and -A
+	OnlyDirs       bool          // --only-dirs flag
+	OnlyFiles      bool          // --only-files flag
+	LargerThan     int64         // --larger-than=SIZE flag, -1 when not given
+	SmallerThan    int64         // --smaller-than=SIZE flag, -1 when not given
+	NewerThan      time.Duration // --newer-than=AGE flag
+	OlderThan      time.Duration // --older-than=AGE flag
+
+	// Layout
+	OnePerLine   bool // -1 flag
+	ForceColumns bool // -C flag, columns even when output isn't a terminal
+	Across       bool // -x flag
+	Width        int  // -w N or --width=N flag, 0 means use the terminal width
+	IconWidth    int  // --icon-width=1|2 flag
+
+	// Colors and icons
+	ColorMode     string // --color=auto|always|never flag
+	IconsMode     string // --icons=auto|always|never flag
+	DircolorsFile string // --dircolors=FILE flag
+
+	ConfigFile string // --config=FILE flag
+
+	// Machine readable output
+	JSON   bool // --json flag
+	NDJSON bool // --ndjson flag, implies JSON
+
+	// Long format
+	Columns []string // --columns=LIST flag, the columns of the long format in order
+
+	// Timestamps
+	TimeField string // --time=WORD, -c or -u flag, which timestamp to show and sort by
+	TimeStyle string // --time-style=STYLE flag
+
+	// Sorting
+	SortBy         string // -S, -t, -X, -v or --sort=WORD flag
+	Reverse        bool   // -r or --reverse flag
+	GroupDirsFirst bool   // --group-directories-first flag
 }
 
-// Parse command line arguments and return a Flags structure
-func parseFlags(args []string) (Flags, []string) {
-	flags := Flags{}
+// Parse command line arguments on top of the given defaults
+func parseFlags(args []string, flags Flags) (Flags, []string) {
 	remaining := []string{args[0]}
 
 	for i := 1; i < len(args); i++ {
@@ -27,37 +88,167 @@ func parseFlags(args []string) (Flags, []string) {
 
 		// Handle combined flags (like -la or -lh)
 		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(arg) > 2 {
-			for _, c := range arg[1:] {
+			for j, c := range arg[1:] {
+				// -w and -I take the rest of the argument, or the next one, as their value
+				if c == 'w' || c == 'I' {
+					value := arg[j+2:]
+					if value == "" {
+						value = nextFlagValue(args, &i, "-"+string(c))
+					}
+					parseShortValueFlag(&flags, c, value)
+					break
+				}
+
 				switch c {
 				case 'l':
 					flags.LongFormat = true
 				case 'a':
-					flags.AllFiles = true
+					flags.AllFiles, flags.AlmostAll = true, false
+				case 'A':
+					flags.AllFiles, flags.AlmostAll = false, true
 				case 'd':
 					flags.DirectoryOnly = true
 				case 'h':
-					flags.HumanReadable = true
+					flags.HumanReadable, flags.SI = true, false
+				case 's':
+					flags.ShowBlocks = true
+				case 'i':
+					flags.Inode = true
+				case 'Z':
+					flags.Context = true
+				case '@':
+					flags.Xattrs = true
+				case 'c':
+					flags.TimeField = TimeChange
+				case 'u':
+					flags.TimeField = TimeAccess
+				case 'R':
+					flags.Recursive = true
+				case 'F':
+					flags.Classify = true
+				case 'L':
+					flags.Dereference = true
+				case 'H':
+					flags.DerefArgs = true
+				case 'S':
+					flags.SortBy = SortSize
+				case 't':
+					flags.SortBy = SortTime
+				case 'X':
+					flags.SortBy = SortExtension
 				case 'v':
-					flags.Version = true
+					flags.SortBy = SortVersion
+				case 'r':
+					flags.Reverse = true
+				case '1':
+					flags.OnePerLine = true
+				case 'x':
+					flags.Across, flags.OnePerLine = true, false
+				case 'C':
+					flags.Across, flags.OnePerLine, flags.ForceColumns = false, false, true
 				}
 			}
 			continue
 		}
 
+		// Handle flags that take a value (like --level=2)
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
+			name, value, _ := strings.Cut(arg, "=")
+			if !parseValueFlag(&flags, name, value) {
+				remaining = append(remaining, arg)
+			}
+			continue
+		}
+
 		switch arg {
 		case "-l":
 			flags.LongFormat = true
-		case "-a":
-			flags.AllFiles = true
+		case "-a", "--all":
+			flags.AllFiles, flags.AlmostAll = true, false
+		case "-A", "--almost-all":
+			flags.AllFiles, flags.AlmostAll = false, true
+		case "--only-dirs":
+			flags.OnlyDirs, flags.OnlyFiles = true, false
+		case "--only-files":
+			flags.OnlyDirs, flags.OnlyFiles = false, true
 		case "-d", "--directory":
 			flags.DirectoryOnly = true
 		case "-h", "--human-readable":
-			flags.HumanReadable = true
+			flags.HumanReadable, flags.SI = true, false
+		case "--si":
+			flags.HumanReadable, flags.SI = false, true
+		case "-s", "--size":
+			flags.ShowBlocks = true
+		case "-i", "--inode":
+			flags.Inode = true
+		case "--detect":
+			flags.Detect = true
+		case "-Z", "--context":
+			flags.Context = true
+		case "-@":
+			flags.Xattrs = true
+		case "--interactive":
+			flags.Interactive = true
+		case "-R", "--recursive":
+			flags.Recursive = true
+		case "-F", "--classify":
+			flags.Classify = true
+		case "-L", "--dereference":
+			flags.Dereference = true
+		case "-H", "--dereference-command-line":
+			flags.DerefArgs = true
+		case "--total-size":
+			flags.TotalSize = true
+		case "--git":
+			flags.Git = true
+		case "--gitignore":
+			flags.GitIgnore = true
+		case "--tree":
+			flags.Tree = true
 		case "--help":
 			flags.Help = true
-		case "-v", "--version":
+		case "--version":
 			flags.Version = true
+		case "-c":
+			flags.TimeField = TimeChange
+		case "-u":
+			flags.TimeField = TimeAccess
+		case "-S":
+			flags.SortBy = SortSize
+		case "-t":
+			flags.SortBy = SortTime
+		case "-X":
+			flags.SortBy = SortExtension
+		case "-v":
+			flags.SortBy = SortVersion
+		case "-U":
+			flags.SortBy = SortNone
+		case "-r", "--reverse":
+			flags.Reverse = true
+		case "--group-directories-first":
+			flags.GroupDirsFirst = true
+		case "--json":
+			flags.JSON = true
+		case "--ndjson":
+			flags.JSON, flags.NDJSON = true, true
+		case "-1":
+			flags.OnePerLine = true
+		case "-x":
+			flags.Across, flags.OnePerLine = true, false
+		case "-C":
+			flags.Across, flags.OnePerLine, flags.ForceColumns = false, false, true
+		case "--color":
+			flags.ColorMode = ModeAlways
+		case "--icons":
+			flags.IconsMode = ModeAlways
+		case "-w", "-I":
+			parseShortValueFlag(&flags, rune(arg[1]), nextFlagValue(args, &i, arg))
 		default:
+			// Long flags that take a value may also have it as the next argument
+			if isInList(arg, separateValueFlags) {
+				parseValueFlag(&flags, arg, nextFlagValue(args, &i, arg))
+				continue
+			}
 			remaining = append(remaining, arg)
 		}
 	}
@@ -65,9 +256,151 @@ func parseFlags(args []string) (Flags, []string) {
 	return flags, remaining
 }
 
+// Set a flag given in --name=value form, returns false if the name is unknown
+func parseValueFlag(flags *Flags, name, value string) bool {
+	switch name {
+	case "--level":
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 1 {
+			flagError("invalid level '%s'", value)
+		}
+		flags.TreeLevel = level
+	case "--sort":
+		switch value {
+		case SortName, SortNone, SortSize, SortTime, SortExtension, SortVersion:
+			flags.SortBy = value
+		default:
+			flagError("invalid argument '%s' for '--sort'", value)
+		}
+	case "--width":
+		flags.Width = parseWidth(value)
+	case "--columns":
+		columns, err := parseColumns(value)
+		if err != nil {
+			flagError("%v", err)
+		}
+		// Picking columns only makes sense for the long format
+		flags.Columns, flags.LongFormat = columns, true
+	case "--time":
+		field, ok := parseTimeField(value)
+		if !ok {
+			flagError("invalid argument '%s' for '--time'", value)
+		}
+		flags.TimeField = field
+	case "--time-style":
+		if !validTimeStyle(value) {
+			flagError("invalid argument '%s' for '--time-style'", value)
+		}
+		flags.TimeStyle = value
+	case "--block-size":
+		size, suffix, err := parseBlockSize(value)
+		if err != nil {
+			flagError("invalid --block-size argument '%s'", value)
+		}
+		flags.BlockSize, flags.BlockSuffix = size, suffix
+		flags.HumanReadable, flags.SI = false, false
+	case "--icon-width":
+		if value != "1" && value != "2" {
+			flagError("invalid icon width '%s', expected 1 or 2", value)
+		}
+		flags.IconWidth, _ = strconv.Atoi(value)
+	case "--dircolors":
+		flags.DircolorsFile = value
+	case "--color", "--colour":
+		flags.ColorMode = parseWhenValue(name, value)
+	case "--icons":
+		flags.IconsMode = parseWhenValue(name, value)
+	case "--config":
+		flags.ConfigFile = value
+	case "--ignore":
+		flags.IgnorePatterns = append(flags.IgnorePatterns, parsePattern(value))
+	case "--hide":
+		flags.HidePatterns = append(flags.HidePatterns, parsePattern(value))
+	case "--larger-than", "--smaller-than":
+		size, err := parseSize(value)
+		if err != nil {
+			flagError("invalid size '%s' for '%s'", value, name)
+		}
+		if name == "--larger-than" {
+			flags.LargerThan = size
+		} else {
+			flags.SmallerThan = size
+		}
+	case "--newer-than", "--older-than":
+		age, err := parseAge(value)
+		if err != nil {
+			flagError("invalid age '%s' for '%s'", value, name)
+		}
+		if name == "--newer-than" {
+			flags.NewerThan = age
+		} else {
+			flags.OlderThan = age
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Long flags whose value can be given as "--flag value" as well as "--flag=value"
+var separateValueFlags = []string{
+	"--level", "--sort", "--width", "--block-size", "--time", "--time-style", "--columns", "--icon-width", "--dircolors", "--config",
+	"--ignore", "--hide", "--larger-than", "--smaller-than", "--newer-than", "--older-than",
+}
+
+// Set a single letter flag that takes a value
+func parseShortValueFlag(flags *Flags, c rune, value string) {
+	switch c {
+	case 'w':
+		flags.Width = parseWidth(value)
+	case 'I':
+		flags.IgnorePatterns = append(flags.IgnorePatterns, parsePattern(value))
+	}
+}
+
+// Check a shell pattern given to -I or --hide
+func parsePattern(pattern string) string {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		flagError("invalid pattern '%s'", pattern)
+	}
+	return pattern
+}
+
+// Take the argument following a flag as its value
+func nextFlagValue(args []string, i *int, name string) string {
+	if *i+1 >= len(args) {
+		flagError("option requires an argument -- '%s'", strings.TrimLeft(name, "-"))
+	}
+	*i++
+	return args[*i]
+}
+
+// Parse a line width given with -w or --width
+func parseWidth(value string) int {
+	width, err := strconv.Atoi(value)
+	if err != nil || width < 1 {
+		flagError("invalid line width: '%s'", value)
+	}
+	return width
+}
+
+// Report an invalid flag value and exit with the usage error code
+func flagError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "lsx: "+format+"\n", args...)
+	fmt.Fprintln(os.Stderr, "Try 'lsx --help' for more information.")
+	os.Exit(2)
+}
+
 // Process flags and execute relevant commands
 func handle_flag(args []string) (Flags, []string) {
-	flags, remaining := parseFlags(args)
+	// The config file provides defaults that command line flags override
+	defaults := Flags{SortBy: SortName, TimeField: TimeModified, ColorMode: ModeAuto, IconsMode: ModeAuto, LargerThan: -1, SmallerThan: -1}
+	if err := loadConfig(args, &defaults); err != nil {
+		fmt.Fprintf(os.Stderr, "lsx: config: %v\n", err)
+		os.Exit(2)
+	}
+
+	flags, remaining := parseFlags(args, defaults)
 
 	if flags.Help {
 		showHelp()
@@ -79,6 +412,14 @@ func handle_flag(args []string) (Flags, []string) {
 		os.Exit(0)
 	}
 
+	if flags.IconWidth > 0 {
+		iconWidth = flags.IconWidth
+	}
+
+	detectEnabled = flags.Detect
+	setupLSColors(flags)
+	setupOutputStyle(&flags)
+
 	return flags, remaining
 }
 
@@ -7,144 +7,194 @@ import (
 	"math"
 	"os"
 	"strings"
-	"unicode/utf8"
 )
 
-// Maximum width for a filename before wrapping
-const maxFilenameWidth = 20
+// Spaces between two columns of the grid
+const columnGap = 2
 
-// print a string slice in the specified number of columns with improved spacing
-func PrintInColumns(items []string, numColumns int) {
-	if len(items) == 0 {
-		return
+// gridLayout describes how a list of cells is arranged on screen
+type gridLayout struct {
+	rows         int
+	columns      int
+	columnWidths []int
+	across       bool // fill rows first (-x) instead of columns
+}
+
+// index returns the item shown at a row and column, or -1 if the cell is empty
+func (g gridLayout) index(row, col, count int) int {
+	idx := row + col*g.rows
+	if g.across {
+		idx = row*g.columns + col
+	}
+	if idx >= count {
+		return -1
+	}
+	return idx
+}
+
+// computeGridLayout finds the most columns that fit the given line width,
+// the way ls -C does, so that no cell ever has to be split
+func computeGridLayout(widths []int, lineWidth int, across bool) gridLayout {
+	count := len(widths)
+
+	// Upper bound: every column at least one character plus the gap
+	maxColumns := count
+	if lineWidth > 0 && lineWidth/(1+columnGap) < maxColumns {
+		maxColumns = max(lineWidth/(1+columnGap), 1)
 	}
 
-	// Calculate how many rows are needed
-	numRows := int(math.Ceil(float64(len(items)) / float64(numColumns)))
-
-	// Find the maximum width needed for each column
-	columnWidths := make([]int, numColumns)
-	for col := 0; col < numColumns; col++ {
-		for row := 0; row < numRows; row++ {
-			idx := row + col*numRows
-			if idx < len(items) {
-				// Get the display width of the string (handling multi-byte characters)
-				displayWidth := utf8.RuneCountInString(items[idx])
-				if displayWidth > maxFilenameWidth {
-					displayWidth = maxFilenameWidth
-				}
-				if displayWidth > columnWidths[col] {
-					columnWidths[col] = displayWidth
-				}
-			}
+	for columns := maxColumns; columns > 1; columns-- {
+		layout := buildGridLayout(widths, columns, across)
+
+		total := columnGap * (layout.columns - 1)
+		for _, w := range layout.columnWidths {
+			total += w
+		}
+		if total <= lineWidth {
+			return layout
 		}
 	}
+	return buildGridLayout(widths, 1, across)
+}
+
+// builds a layout with the given number of columns and measures each column
+func buildGridLayout(widths []int, columns int, across bool) gridLayout {
+	count := len(widths)
+	rows := int(math.Ceil(float64(count) / float64(columns)))
+
+	// Filling by columns can leave trailing columns empty, drop them
+	if !across {
+		columns = int(math.Ceil(float64(count) / float64(rows)))
+	}
 
-	for row := 0; row < numRows; row++ {
-		// Track if any item in this row needs an extra line
-		needsExtraLine := false
-		rowContent := make([]string, numColumns)
-		overflowContent := make([]string, numColumns)
-
-		for col := 0; col < numColumns; col++ {
-			idx := row + col*numRows
-			if idx < len(items) {
-				item := items[idx]
-
-				// Handle long filenames
-				if utf8.RuneCountInString(item) > maxFilenameWidth {
-					needsExtraLine = true
-					displayPart := truncateString(item, maxFilenameWidth)
-					overflowPart := item[len(displayPart):]
-
-					rowContent[col] = displayPart
-					overflowContent[col] = overflowPart
-				} else {
-					rowContent[col] = item
-					overflowContent[col] = ""
-				}
+	layout := gridLayout{rows: rows, columns: columns, across: across}
+	layout.columnWidths = make([]int, columns)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < columns; col++ {
+			idx := layout.index(row, col, count)
+			if idx >= 0 && widths[idx] > layout.columnWidths[col] {
+				layout.columnWidths[col] = widths[idx]
 			}
 		}
+	}
+	return layout
+}
 
-		// Print main content for this row
-		for col := 0; col < numColumns; col++ {
-			if col < len(rowContent) && rowContent[col] != "" {
-				item := rowContent[col]
-
-				// Add padding for all columns except the last one
-				if col < numColumns-1 {
-					padding := columnWidths[col] - utf8.RuneCountInString(item) + 4
-					fmt.Print(item + strings.Repeat(" ", padding))
-				} else {
-					fmt.Print(item)
-				}
+// printGrid prints pre-rendered cells using a layout. widths holds the
+// display width of each cell, since cells may contain color escapes.
+func printGrid(cells []string, widths []int, layout gridLayout) {
+	for row := 0; row < layout.rows; row++ {
+		line := ""
+		for col := 0; col < layout.columns; col++ {
+			idx := layout.index(row, col, len(cells))
+			if idx < 0 {
+				continue
 			}
-		}
-		fmt.Print("\n")
-
-		// If there's overflow content, print it on the next line
-		if needsExtraLine {
-			for col := 0; col < numColumns; col++ {
-				if col < len(overflowContent) && overflowContent[col] != "" {
-					// Truncate again if it's still too long
-					overflow := overflowContent[col]
-					if utf8.RuneCountInString(overflow) > maxFilenameWidth {
-						overflow = truncateString(overflow, maxFilenameWidth-3) + "..."
-					}
-
-					// Add padding similar to main content
-					if col < numColumns-1 {
-						padding := columnWidths[col] - utf8.RuneCountInString(overflow) + 4
-						fmt.Print(overflow + strings.Repeat(" ", padding))
-					} else {
-						fmt.Print(overflow)
-					}
-				} else if col < numColumns-1 {
-					// Empty cell but need padding
-					fmt.Print(strings.Repeat(" ", columnWidths[col]+4))
-				}
+
+			// Pad every cell except the last one on the line
+			next := layout.index(row, col+1, len(cells))
+			if col < layout.columns-1 && next >= 0 {
+				line += cells[idx] + spaces(layout.columnWidths[col]-widths[idx]+columnGap)
+			} else {
+				line += cells[idx]
 			}
-			fmt.Print("\n")
 		}
+		fmt.Println(line)
+	}
+}
+
+// print a string slice in as many columns as fit the given line width
+func PrintInColumns(items []string, lineWidth int) {
+	if len(items) == 0 {
+		return
+	}
 
-		// End of row - add another line break for spacing between rows
-		fmt.Print("\n")
+	widths := make([]int, len(items))
+	for i, item := range items {
+		widths[i] = displayWidth(item)
 	}
+
+	printGrid(items, widths, computeGridLayout(widths, lineWidth, false))
 }
 
-// determines appropriate icon and color based on file type
+// determines appropriate icon and color based on file type, returned as keys
+// into the Color and Icons maps (colors may combine several keys, like "cyan bold")
 func getFileTypeColorAndIcon(file os.FileInfo, ext string, isDir bool) (string, string) {
 
+	mode := file.Mode()
 	if isDir {
-		return Color["blue"], Icons["folder"]
+		// Directories anyone can write to stand out, like ls colors /tmp
+		otherWritable := mode.Perm()&0002 != 0
+		sticky := mode&os.ModeSticky != 0
+		switch {
+		case otherWritable && sticky:
+			return "black BG_green", "open_folder"
+		case otherWritable:
+			return "blue BG_green", "open_folder"
+		case sticky:
+			return "white BG_blue", "sticky_folder"
+		}
+		return "blue", "folder"
+	}
+
+	// Symlinks, told apart by what they point to
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := statPath(entryPath(file))
+		switch {
+		case err != nil:
+			return "bright_red", "broken_link"
+		case target.IsDir():
+			return "cyan bold", "symlink_dir"
+		default:
+			return "cyan bold", "symlink"
+		}
 	}
 
-	// Executable handling
-	if file.Mode()&0111 != 0 {
-		return Color["bright_green"], Icons["executable"]
+	// Special files
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "yellow BG_black", "pipe"
+	case mode&os.ModeSocket != 0:
+		return "magenta bold", "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "yellow bold BG_black", "char_device"
+	case mode&os.ModeDevice != 0:
+		return "yellow bold BG_black", "block_device"
+	}
+
+	// Executable handling, with setuid and setgid programs marked
+	if mode&0111 != 0 {
+		switch {
+		case mode&os.ModeSetuid != 0:
+			return "white BG_red", "setuid"
+		case mode&os.ModeSetgid != 0:
+			return "black BG_yellow", "setuid"
+		}
+		return "bright_green", "executable"
 	}
 
 	name := file.Name()
 	switch name {
 	case "go.mod", "go.sum":
-		return Color["cyan"], Icons["go.mod"]
+		return "cyan", "go.mod"
 	case "package.json":
-		return Color["bright_green"] + Color["bold"], Icons["package.json"]
+		return "bright_green bold", "package.json"
 	case "tailwind.config.js", "tailwind.config.ts":
-		return Color["bright_blue"], Icons["tailwind"]
+		return "bright_blue", "tailwind"
 	case "vue.config.js":
-		return Color["bright_blue"], Icons["vue.config.js"]
+		return "bright_blue", "vue.config.js"
 	case ".eslintrc.js", ".eslintrc.json", ".eslintrc.yml", ".eslintrc.yaml":
-		return Color["bright_blue"], Icons["eslint"]
+		return "bright_blue", "eslint"
 	}
 	// Check for a direct icon match
-	if icon, exists := Icons[ext]; exists {
-		return getColorForFileType(ext), icon
+	if _, exists := Icons[ext]; exists {
+		return getColorForFileType(ext), ext
 	}
 
-	return Color["dim"], Icons["default"]
+	return "dim", "default"
 }
 
+// picks the color key for an extension
 func getColorForFileType(ext string) string {
 	switch {
 	// Programming languages
@@ -153,117 +203,134 @@ func getColorForFileType(ext string) string {
 		// Different colors for different programming languages
 		switch ext {
 		case ".java", ".go", ".jsx":
-			return Color["cyan"]
+			return "cyan"
 		case ".c", ".py", ".asm", ".dart":
-			return Color["bright_blue"]
+			return "bright_blue"
 		case ".swift", ".rs":
-			return Color["yellow"]
+			return "yellow"
 		case ".js", ".lock":
-			return Color["bright_yellow"]
+			return "bright_yellow"
 		case ".ts", ".tsx":
-			return Color["blue"]
+			return "blue"
 		case ".kt":
-			return Color["red"]
+			return "red"
 		case ".cpp", ".cs":
-			return Color["bright_green"]
+			return "bright_green"
 		case ".rb":
-			return Color["bright_red"]
+			return "bright_red"
 		case ".php":
-			return Color["bright_magenta"]
+			return "bright_magenta"
 		case ".ex", ".exs":
-			return Color["cyan"] // Elixir
+			return "cyan" // Elixir
 		case ".hs":
-			return Color["yellow"] // Haskell
+			return "yellow" // Haskell
 		case ".pl":
-			return Color["bright_cyan"] // Perl
+			return "bright_cyan" // Perl
 		case ".r":
-			return Color["green"] // R
+			return "green" // R
 		case ".scala":
-			return Color["bright_magenta"]
+			return "bright_magenta"
 		case ".sh", ".bash", ".zsh":
-			return Color["green"]
+			return "green"
 		case ".lua":
-			return Color["blue"]
+			return "blue"
 		case ".d":
-			return Color["magenta"]
+			return "magenta"
 		case ".m", ".mat", ".cbl":
-			return Color["bright_yellow"]
+			return "bright_yellow"
 		case ".ps1":
-			return Color["bright_blue"]
+			return "bright_blue"
 		case ".jil":
-			return Color["red"]
+			return "red"
 		case ".ml":
-			return Color["yellow"]
+			return "yellow"
 		case ".f90":
-			return Color["cyan"]
+			return "cyan"
 		case ".vim", ".vimrc":
-			return Color["green"]
+			return "green"
 		case ".ads":
-			return Color["bright_magenta"]
+			return "bright_magenta"
 		case ".sql":
-			return Color["bright_cyan"]
+			return "bright_cyan"
 		default:
-			return Color["bright_white"]
+			return "bright_white"
 		}
 
 	// Web technologies
 	case isInList(ext, []string{".html", ".css", ".scss", ".sass", ".less", ".vue"}):
 		switch ext {
 		case ".html":
-			return Color["yellow"] + Color["bold"]
+			return "yellow bold"
 		case ".css", ".scss", ".sass", ".less":
-			return Color["bright_magenta"]
+			return "bright_magenta"
 		default:
-			return Color["yellow"]
+			return "yellow"
 		}
 
 	// Data formats
 	case isInList(ext, []string{".json", ".xml", ".yaml", ".yml", ".toml", ".csv"}):
-		return Color["bright_yellow"]
+		return "bright_yellow"
 
 	// Documents and text
 	case isInList(ext, []string{".txt", ".md", ".pdf", ".doc", ".docx", ".odt", ".xls", ".xlsx", ".ppt", ".pptx", ".log", ".ipynb"}):
 		switch ext {
 		case ".pdf", ".doc", ".docx", ".odt":
-			return Color["bright_red"]
+			return "bright_red"
 		case ".xls", ".xlsx", ".ods":
-			return Color["bright_green"]
+			return "bright_green"
 		case ".ppt", ".pptx", ".odp":
-			return Color["bright_yellow"]
+			return "bright_yellow"
 		case ".log":
-			return Color["cyan"]
+			return "cyan"
 		case ".ipynb":
-			return Color["yellow"] + Color["bold"]
+			return "yellow bold"
 		default:
-			return Color["white"]
+			return "white"
 		}
 
 	// Media files
 	case isInList(ext, []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp"}):
-		return Color["bright_cyan"]
+		return "bright_cyan"
 
 	case isInList(ext, []string{".mp3", ".wav", ".ogg", ".flac", ".aac", ".m4a", ".epub", ".mobi"}):
-		return Color["magenta"]
+		return "magenta"
 
 	case isInList(ext, []string{".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm"}):
-		return Color["bright_magenta"]
+		return "bright_magenta"
 
 	// Archives
 	case isInList(ext, []string{".zip", ".tar", ".gz", ".bz2", ".7z", ".rar", ".xz"}):
-		return Color["red"]
+		return "red"
 
 	// Scripts and configs
 	case isInList(ext, []string{".sh", ".bash", ".zsh", ".fish", ".conf", ".cfg", ".ini", ".dockerfile", ".makefile", ".cmake", ".gitignore", ".gitattributes"}):
-		return Color["green"]
+		return "green"
 
 	// Default for unknown types
 	default:
-		return Color["dim"]
+		return "dim"
+	}
+}
+
+// resolveColor turns a color key like "yellow bold" into its escape sequences
+func resolveColor(key string) string {
+	color := ""
+	for _, name := range strings.Fields(key) {
+		color += Color[name]
 	}
+	return color
 }
 
 // Helper functions
 
+// spaces returns n spaces, or nothing when n isn't positive
+func spaces(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(" ", n)
+}
+
 // isInList checks if a string is in a slice of strings
 func isInList(item string, list []string) bool {
 	for _, i := range list {
@@ -273,13 +340,3 @@ func isInList(item string, list []string) bool {
 	}
 	return false
 }
-
-// safely truncates a string to the given width, respecting UTF-8 characters
-func truncateString(s string, maxWidth int) string {
-	if utf8.RuneCountInString(s) <= maxWidth {
-		return s
-	}
-
-	runes := []rune(s)
-	return string(runes[:maxWidth])
-}
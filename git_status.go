@@ -0,0 +1,236 @@
+// git_status.go
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitStatus is a file's two-letter status from `git status --short`:
+// the index (staged) state and the working tree state
+type gitStatus struct {
+	index    byte
+	worktree byte
+}
+
+// Status shown for tracked files without changes
+var gitClean = gitStatus{'-', '-'}
+
+// gitRepo holds the status of every changed file in one repository
+type gitRepo struct {
+	root     string
+	statuses map[string]gitStatus // by path relative to root, "dir/" for ignored directories
+}
+
+// Repositories found so far, by the directory that was looked up.
+// A nil value means the directory isn't inside a repository.
+var gitRepoCache = map[string]*gitRepo{}
+
+// Whether git was missing, so the warning is only printed once
+var gitMissing = false
+
+// Order of importance when summarizing a directory, most important first
+const gitStatusPriority = "UMADRCT?!-"
+
+// formatGitBadge renders an entry's git status as a colored two-letter
+// column followed by a space, or nothing if --git isn't given
+func formatGitBadge(file os.FileInfo, flags Flags) string {
+	if !flags.Git {
+		return ""
+	}
+	return gitBadge(file) + " "
+}
+
+// gitBadge returns the two status characters of a file, colored
+func gitBadge(file os.FileInfo) string {
+	status, ok := getGitStatus(file)
+	if !ok {
+		// Outside a repository, keep the column aligned with blanks
+		return "  "
+	}
+	return colorGitChar(status.index, true) + colorGitChar(status.worktree, false) + Color["reset"]
+}
+
+// getGitStatus finds the status of a file, summarizing the children of a directory
+func getGitStatus(file os.FileInfo) (gitStatus, bool) {
+	path := realPath(entryPath(file))
+
+	// A directory is looked up from itself, so "." at the top of a
+	// repository belongs to it; .git has no work tree and uses its parent
+	var repo *gitRepo
+	if file.IsDir() {
+		repo = findGitRepo(path)
+	}
+	if repo == nil {
+		repo = findGitRepo(filepath.Dir(path))
+	}
+	if repo == nil {
+		return gitStatus{}, false
+	}
+
+	rel, err := filepath.Rel(repo.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return gitStatus{}, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// Inside an ignored directory everything is ignored
+	for dir := rel; dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if status, ok := repo.statuses[dir+"/"]; ok && status.worktree == '!' {
+			return status, true
+		}
+	}
+
+	if status, ok := repo.statuses[rel]; ok {
+		return status, true
+	}
+	if !file.IsDir() {
+		return gitClean, true
+	}
+
+	// A directory shows the most important status among its children
+	summary := gitClean
+	prefix := rel + "/"
+	if rel == "." {
+		prefix = ""
+	}
+	for childPath, status := range repo.statuses {
+		// Ignored files inside a directory don't make it interesting
+		if !strings.HasPrefix(childPath, prefix) || status.worktree == '!' {
+			continue
+		}
+		summary.index = moreImportantGitChar(summary.index, status.index)
+		summary.worktree = moreImportantGitChar(summary.worktree, status.worktree)
+	}
+	return summary, true
+}
+
+// findGitRepo returns the repository a directory belongs to, asking git
+// once per directory and once per repository
+func findGitRepo(dir string) *gitRepo {
+	if repo, ok := gitRepoCache[dir]; ok {
+		return repo
+	}
+
+	var repo *gitRepo
+	if output, err := runGit(dir, "rev-parse", "--show-toplevel"); err == nil {
+		root := strings.TrimSpace(string(output))
+		repo = loadGitRepo(root)
+	}
+	gitRepoCache[dir] = repo
+	return repo
+}
+
+// reads the status of a repository, reusing it if another directory
+// already found the same root
+func loadGitRepo(root string) *gitRepo {
+	for _, repo := range gitRepoCache {
+		if repo != nil && repo.root == root {
+			return repo
+		}
+	}
+
+	repo := &gitRepo{root: root, statuses: map[string]gitStatus{}}
+	output, err := runGit(root, "status", "--porcelain=v1", "-z", "--ignored=matching", "--untracked-files=all")
+	if err != nil {
+		return repo
+	}
+
+	// Records are "XY path", renames and copies are followed by the old path
+	records := bytes.Split(output, []byte{0})
+	for i := 0; i < len(records); i++ {
+		record := string(records[i])
+		if len(record) < 4 {
+			continue
+		}
+		repo.statuses[record[3:]] = gitStatus{index: record[0], worktree: record[1]}
+		if record[0] == 'R' || record[0] == 'C' {
+			i++
+		}
+	}
+	return repo
+}
+
+// runs a git command in a directory, warning once if git isn't installed
+func runGit(dir string, args ...string) ([]byte, error) {
+	if gitMissing {
+		return nil, exec.ErrNotFound
+	}
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.Output()
+	if err != nil && isGitNotFound(err) {
+		gitMissing = true
+		fmt.Fprintln(os.Stderr, "lsx: --git: git executable not found")
+	}
+	return output, err
+}
+
+func isGitNotFound(err error) bool {
+	execErr, ok := err.(*exec.Error)
+	return ok && execErr.Err == exec.ErrNotFound
+}
+
+// realPath resolves symlinks in the directory part of a path, so it can be
+// compared with the repository root git reports
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(abs))
+	if err != nil {
+		return abs
+	}
+	return filepath.Join(dir, filepath.Base(abs))
+}
+
+// picks the status letter that matters more for a directory summary
+func moreImportantGitChar(a, b byte) byte {
+	if b == ' ' {
+		return a
+	}
+	rankA := strings.IndexByte(gitStatusPriority, a)
+	rankB := strings.IndexByte(gitStatusPriority, b)
+	if rankB >= 0 && (rankA < 0 || rankB < rankA) {
+		return b
+	}
+	return a
+}
+
+// colors one status letter: staged changes green, unstaged red,
+// untracked and conflicted files stand out, ignored ones are dimmed
+func colorGitChar(c byte, staged bool) string {
+	color := ""
+	switch c {
+	case ' ', '-':
+		color, c = Color["dim"], '-'
+	case 'U':
+		color = Color["bright_red"] + Color["bold"]
+	case '?':
+		color = Color["bright_magenta"]
+	case '!':
+		color = Color["dim"]
+	default:
+		if staged {
+			color = Color["green"]
+		} else {
+			color = Color["red"]
+		}
+	}
+	return Color["reset"] + color + string(c)
+}
+
+// gitStatusText returns the two-letter status for JSON output
+func gitStatusText(file os.FileInfo) string {
+	status, ok := getGitStatus(file)
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(string([]byte{status.index, status.worktree}), " ", "-")
+}
@@ -0,0 +1,273 @@
+// gitignore.go
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignorePattern is one line of a .gitignore file
+type ignorePattern struct {
+	pattern  string
+	base     string // directory of the .gitignore, relative to the repo root ("" for the root)
+	negate   bool   // "!pattern" re-includes what an earlier pattern excluded
+	dirOnly  bool   // "pattern/" only matches directories
+	anchored bool   // a "/" in the pattern makes it relative to base instead of any level
+}
+
+// ignoreRepo holds the ignore rules of one repository
+type ignoreRepo struct {
+	root     string
+	excludes []ignorePattern            // global excludes file and .git/info/exclude
+	dirs     map[string][]ignorePattern // .gitignore patterns by directory, loaded on demand
+}
+
+// Repositories found so far, by the directory that was looked up.
+// A nil value means the directory isn't inside a repository.
+var ignoreRepoCache = map[string]*ignoreRepo{}
+
+// isGitIgnored reports whether --gitignore should hide a path. Like git, a
+// file inside an excluded directory can't be re-included by a negation.
+func isGitIgnored(path string, isDir bool) bool {
+	// The repository's own .git directory is never part of the work tree
+	if filepath.Base(path) == ".git" {
+		return true
+	}
+
+	abs := realPath(path)
+	repo := findIgnoreRepo(filepath.Dir(abs))
+	if repo == nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(repo.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(parts); i++ {
+		last := i == len(parts)
+		if repo.isExcluded(strings.Join(parts[:i], "/"), isDir || !last) {
+			return true
+		}
+	}
+	return false
+}
+
+// applies every pattern that can affect a path; the last match wins
+func (repo *ignoreRepo) isExcluded(rel string, isDir bool) bool {
+	excluded := false
+	check := func(patterns []ignorePattern) {
+		for _, p := range patterns {
+			if p.matches(rel, isDir) {
+				excluded = !p.negate
+			}
+		}
+	}
+
+	// Lowest precedence first: excludes, then .gitignore files from the root down
+	check(repo.excludes)
+	check(repo.patternsFor(""))
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		check(repo.patternsFor(strings.Join(parts[:i], "/")))
+	}
+	return excluded
+}
+
+// loads the .gitignore of a directory inside the repository
+func (repo *ignoreRepo) patternsFor(dir string) []ignorePattern {
+	if patterns, ok := repo.dirs[dir]; ok {
+		return patterns
+	}
+	patterns := readIgnoreFile(filepath.Join(repo.root, filepath.FromSlash(dir), ".gitignore"), dir)
+	repo.dirs[dir] = patterns
+	return patterns
+}
+
+// reports whether a pattern matches a path relative to the repository root
+func (p ignorePattern) matches(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	// Patterns only apply below the directory of their .gitignore
+	if p.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, p.base+"/"); !ok {
+			return false
+		}
+	}
+
+	if p.anchored {
+		return matchIgnoreGlob(strings.Split(p.pattern, "/"), strings.Split(rel, "/"))
+	}
+	// Without a slash the pattern matches the name at any level
+	matched, _ := filepath.Match(p.pattern, filepath.Base(rel))
+	return matched
+}
+
+// matches pattern segments against path segments, where a "**" segment
+// matches any number of directories
+func matchIgnoreGlob(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		// A trailing "/**" matches everything inside, but not the directory itself
+		if len(pattern) == 1 {
+			return len(path) > 0
+		}
+		for i := 0; i <= len(path); i++ {
+			if matchIgnoreGlob(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+		return false
+	}
+	return matchIgnoreGlob(pattern[1:], path[1:])
+}
+
+// findIgnoreRepo finds the repository containing a directory by looking
+// for a .git entry in it and its parents
+func findIgnoreRepo(dir string) *ignoreRepo {
+	if repo, ok := ignoreRepoCache[dir]; ok {
+		return repo
+	}
+
+	var repo *ignoreRepo
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+		repo = newIgnoreRepo(dir)
+	} else if parent := filepath.Dir(dir); parent != dir {
+		repo = findIgnoreRepo(parent)
+	}
+	ignoreRepoCache[dir] = repo
+	return repo
+}
+
+// sets up a repository with its global and per-repository excludes
+func newIgnoreRepo(root string) *ignoreRepo {
+	repo := &ignoreRepo{root: root, dirs: map[string][]ignorePattern{}}
+	if path := globalExcludesFile(); path != "" {
+		repo.excludes = readIgnoreFile(path, "")
+	}
+	repo.excludes = append(repo.excludes, readIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), "")...)
+	return repo
+}
+
+// globalExcludesFile returns core.excludesFile from the user's git config,
+// or git's default of $XDG_CONFIG_HOME/git/ignore
+func globalExcludesFile() string {
+	home, _ := os.UserHomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	for _, config := range []string{filepath.Join(home, ".gitconfig"), filepath.Join(configHome, "git", "config")} {
+		if path := readExcludesFileSetting(config); path != "" {
+			if rest, ok := strings.CutPrefix(path, "~/"); ok {
+				path = filepath.Join(home, rest)
+			}
+			return path
+		}
+	}
+
+	if configHome == "" {
+		return ""
+	}
+	return filepath.Join(configHome, "git", "ignore")
+}
+
+// finds "excludesfile = path" in the [core] section of a git config file
+func readExcludesFileSetting(configPath string) string {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inCore := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inCore = strings.EqualFold(strings.Trim(line, "[] "), "core")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if inCore && ok && strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// readIgnoreFile parses a .gitignore style file; a missing file has no patterns
+func readIgnoreFile(path, base string) []ignorePattern {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parses one line of a .gitignore file
+func parseIgnoreLine(line, base string) (ignorePattern, bool) {
+	// Trailing spaces are dropped unless escaped with a backslash
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	p := ignorePattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A slash at the start or in the middle anchors the pattern
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	if line == "" {
+		return ignorePattern{}, false
+	}
+	p.pattern = line
+	return p, true
+}
@@ -0,0 +1,225 @@
+// glob.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isGlobPattern reports whether an argument has wildcards to expand.
+// Patterns only reach lsx when quoted, otherwise the shell expands them.
+func isGlobPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[") || hasBraces(arg)
+}
+
+// needsExpansion reports whether an argument should be globbed: it has
+// wildcards and isn't itself the name of a file, like "photo[1].jpg"
+// handed over by the shell after expanding "photo*"
+func needsExpansion(arg string) bool {
+	if !isGlobPattern(arg) {
+		return false
+	}
+	_, err := lstatPath(arg)
+	return err != nil
+}
+
+// expandGlob returns the paths matching a pattern, sorted and without
+// duplicates. Besides *, ? and [classes] it supports {a,b} alternatives
+// and ** to match any number of directories.
+func expandGlob(pattern string, flags Flags) []string {
+	var matches []string
+	for _, alternative := range expandBraces(pattern) {
+		matches = append(matches, globPath(alternative, flags)...)
+	}
+
+	sort.Strings(matches)
+	unique := matches[:0]
+	for i, match := range matches {
+		if i == 0 || match != matches[i-1] {
+			unique = append(unique, match)
+		}
+	}
+	return unique
+}
+
+// matches a brace-free pattern one path segment at a time
+func globPath(pattern string, flags Flags) []string {
+	dir := ""
+	if strings.HasPrefix(pattern, "/") {
+		dir = "/"
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return globSegments(dir, segments, flags)
+}
+
+// matches the remaining segments of a pattern below dir
+func globSegments(dir string, segments []string, flags Flags) []string {
+	if len(segments) == 0 {
+		return []string{dir}
+	}
+	segment, rest := segments[0], segments[1:]
+
+	// ** matches zero or more directories
+	if segment == "**" {
+		var results []string
+		if len(rest) > 0 {
+			results = globSegments(dir, rest, flags)
+		}
+		for _, entry := range readDirQuietly(dir) {
+			if isHiddenFromGlob(entry.Name(), segment, flags) {
+				continue
+			}
+			path := globJoin(dir, entry.Name())
+			if len(rest) == 0 {
+				results = append(results, path)
+			}
+			// DirEntry.IsDir doesn't follow symlinks, so ** can't loop
+			if entry.IsDir() {
+				results = append(results, globSegments(path, segments, flags)...)
+			}
+		}
+		return results
+	}
+
+	// Literal segments don't need a directory read
+	if !strings.ContainsAny(segment, "*?[") {
+		path := globJoin(dir, segment)
+		if _, err := os.Lstat(path); err != nil {
+			return nil
+		}
+		if len(rest) > 0 && !isDirectory(path) {
+			return nil
+		}
+		return globSegments(path, rest, flags)
+	}
+
+	var results []string
+	for _, entry := range readDirQuietly(dir) {
+		name := entry.Name()
+		if isHiddenFromGlob(name, segment, flags) {
+			continue
+		}
+		if matched, _ := filepath.Match(segment, name); !matched {
+			continue
+		}
+
+		path := globJoin(dir, name)
+		if len(rest) > 0 && !isDirectory(path) {
+			continue
+		}
+		results = append(results, globSegments(path, rest, flags)...)
+	}
+	return results
+}
+
+// Like the shell, wildcards don't match a leading dot unless the pattern
+// starts with one too, or -a or -A is given
+func isHiddenFromGlob(name, segment string, flags Flags) bool {
+	return strings.HasPrefix(name, ".") && !strings.HasPrefix(segment, ".") && !flags.AllFiles && !flags.AlmostAll
+}
+
+// reads a directory for matching, treating unreadable ones as empty
+func readDirQuietly(dir string) []os.DirEntry {
+	if dir == "" {
+		dir = "."
+	}
+	entries, _ := os.ReadDir(dir)
+	return entries
+}
+
+// reports whether a path is a directory, following symlinks
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// joins a matched name onto the directory part of a pattern
+func globJoin(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return joinPath(dir, name)
+}
+
+// hasBraces reports whether a pattern has a {a,b} group to expand
+func hasBraces(pattern string) bool {
+	open, close := findBraceGroup(pattern)
+	return open >= 0 && close > open
+}
+
+// expandBraces turns "src/{a,b}/*.go" into "src/a/*.go" and "src/b/*.go".
+// Groups may be nested; braces without a comma are kept as they are.
+func expandBraces(pattern string) []string {
+	open, close := findBraceGroup(pattern)
+	if open < 0 {
+		return []string{pattern}
+	}
+
+	prefix, body, suffix := pattern[:open], pattern[open+1:close], pattern[close+1:]
+
+	var results []string
+	for _, alternative := range splitBraceBody(body) {
+		results = append(results, expandBraces(prefix+alternative+suffix)...)
+	}
+	return results
+}
+
+// finds the first brace group that has a comma at its top level
+func findBraceGroup(pattern string) (int, int) {
+	for open := 0; open < len(pattern); open++ {
+		if pattern[open] != '{' {
+			continue
+		}
+
+		depth := 0
+		hasComma := false
+		for i := open; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case ',':
+				if depth == 1 {
+					hasComma = true
+				}
+			}
+			if depth == 0 {
+				if hasComma {
+					return open, i
+				}
+				break
+			}
+		}
+	}
+	return -1, -1
+}
+
+// splits the inside of a brace group on its top level commas
+func splitBraceBody(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, body[start:])
+}
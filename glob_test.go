@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"plain.txt", []string{"plain.txt"}},
+		{"src/{a,b}/*.go", []string{"src/a/*.go", "src/b/*.go"}},
+		{"{a,b}{1,2}", []string{"a1", "a2", "b1", "b2"}},
+		{"x{a,{b,c}}y", []string{"xay", "xby", "xcy"}},
+		{"file{}.txt", []string{"file{}.txt"}},
+		{"notes{a}.txt", []string{"notes{a}.txt"}},
+		{"{,.}rc", []string{"rc", ".rc"}},
+	}
+	for _, test := range tests {
+		if got := expandBraces(test.pattern); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestFindBraceGroup(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		open, close int
+	}{
+		{"none", -1, -1},
+		{"{a,b}", 0, 4},
+		{"x{a}y{b,c}", 5, 9},
+		{"{a,{b,c}}", 0, 8},
+		{"{unclosed,", -1, -1},
+	}
+	for _, test := range tests {
+		open, close := findBraceGroup(test.pattern)
+		if open != test.open || close != test.close {
+			t.Errorf("findBraceGroup(%q) = %d, %d, want %d, %d", test.pattern, open, close, test.open, test.close)
+		}
+	}
+}
+
+func TestNeedsExpansionKeepsLiteralNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"photo[1].jpg", "notes{a,b}.txt", "what?"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if needsExpansion(path) {
+			t.Errorf("needsExpansion(%q) = true for an existing file", name)
+		}
+	}
+
+	if !needsExpansion(filepath.Join(dir, "photo*")) {
+		t.Errorf("needsExpansion of a pattern without a matching literal file = false")
+	}
+	if needsExpansion(filepath.Join(dir, "missing.jpg")) {
+		t.Errorf("needsExpansion of a name without wildcards = true")
+	}
+}
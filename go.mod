@@ -1,3 +1,8 @@
 module github.com/architmishra-15/lsx
 
 go 1.24.2
+
+require (
+	github.com/ulikunitz/xz v0.5.17
+	golang.org/x/sys v0.32.0
+)
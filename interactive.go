@@ -0,0 +1,608 @@
+// interactive.go
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sort orders that "s" cycles through in the browser
+var browserSorts = []string{SortName, SortSize, SortTime, SortExtension, SortVersion}
+
+// How much of a file the preview pane reads
+const previewBytes = 64 * 1024
+
+// Escape sequences the browser draws with. Selection and chrome use them
+// directly so they stay visible with --color=never.
+const (
+	escReset       = "\x1b[0m"
+	escReverse     = "\x1b[7m"
+	escBold        = "\x1b[1m"
+	escDim         = "\x1b[2m"
+	escClearLine   = "\x1b[K"
+	escHome        = "\x1b[H"
+	escAltScreen   = "\x1b[?1049h"
+	escMainScreen  = "\x1b[?1049l"
+	escHideCursor  = "\x1b[?25l"
+	escShowCursor  = "\x1b[?25h"
+	escClearScreen = "\x1b[2J"
+)
+
+// browser is the state of the --interactive file browser
+type browser struct {
+	tty   *os.File
+	flags Flags
+
+	dir     string        // absolute path of the directory shown
+	entries []os.FileInfo // everything in it, sorted
+	visible []os.FileInfo // the entries matching the filter
+	cursor  int           // index into visible
+	offset  int           // first visible entry on screen
+
+	filter    string // fuzzy filter typed after "/"
+	filtering bool   // keys go to the filter
+	preview   bool
+	sortIndex int
+	message   string // error shown in the status line
+
+	rows, cols int
+}
+
+// runInteractive browses from the given path until the user quits, then
+// prints the chosen path so a shell function can cd to it:
+//
+//	cd "$(lsx --interactive)"
+func runInteractive(path string, flags Flags) {
+	// The browser draws on the terminal itself, leaving stdout for the result
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		reportError(exitTrouble, "cannot open terminal: %s", describeError(err))
+		return
+	}
+	defer tty.Close()
+
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		reportError(exitTrouble, "cannot access '%s': %s", path, describeError(err))
+		return
+	}
+	if info, err := statPath(dir); err != nil {
+		reportError(exitTrouble, "cannot access '%s': %s", path, describeError(err))
+		return
+	} else if !info.IsDir() && !isListableArchive(dir, info) {
+		dir = filepath.Dir(dir)
+	}
+
+	restore, err := makeRaw(tty.Fd())
+	if err != nil {
+		reportError(exitTrouble, "cannot use terminal: %s", describeError(err))
+		return
+	}
+
+	// The browser's own view settings; -a starts with hidden files shown
+	flags.AlmostAll = flags.AllFiles || flags.AlmostAll
+	flags.AllFiles, flags.Recursive, flags.Tree = false, false, false
+	b := &browser{tty: tty, flags: flags, preview: true}
+	for i, sortBy := range browserSorts {
+		if sortBy == flags.SortBy {
+			b.sortIndex = i
+		}
+	}
+	b.changeDir(dir, "")
+
+	fmt.Fprint(tty, escAltScreen+escHideCursor)
+	selected, ok := b.run()
+	fmt.Fprint(tty, escShowCursor+escMainScreen)
+	restore()
+
+	if ok {
+		fmt.Println(selected)
+	} else {
+		exitStatus = exitMinor
+	}
+}
+
+// run handles key presses until the browser is done, returning the
+// chosen path, or false if the user cancelled
+func (b *browser) run() (string, bool) {
+	keys := make(chan string)
+	go readKeys(b.tty, keys)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	for {
+		b.draw()
+		select {
+		case key, open := <-keys:
+			if !open {
+				return "", false
+			}
+			if selected, done, ok := b.handleKey(key); done {
+				return selected, ok
+			}
+		case <-resize:
+		}
+	}
+}
+
+// readKeys turns input from the terminal into key names, closing the
+// channel when the terminal goes away
+func readKeys(tty io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		input := buf[:n]
+		for len(input) > 0 {
+			key, size := parseKey(input)
+			input = input[size:]
+			if key != "" {
+				keys <- key
+			}
+		}
+	}
+}
+
+// Escape sequences sent by the keys the browser knows, in both the normal
+// and the application cursor key modes
+var keySequences = map[string]string{
+	"\x1b[A": "up", "\x1bOA": "up",
+	"\x1b[B": "down", "\x1bOB": "down",
+	"\x1b[C": "right", "\x1bOC": "right",
+	"\x1b[D": "left", "\x1bOD": "left",
+	"\x1b[H": "home", "\x1bOH": "home", "\x1b[1~": "home",
+	"\x1b[F": "end", "\x1bOF": "end", "\x1b[4~": "end",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdn",
+}
+
+// parseKey splits the first key press off the input, naming special keys
+// like "up" or "enter" and returning printable ones as themselves
+func parseKey(input []byte) (string, int) {
+	if input[0] == 0x1b {
+		for seq, name := range keySequences {
+			if bytes.HasPrefix(input, []byte(seq)) {
+				return name, len(seq)
+			}
+		}
+		if len(input) == 1 {
+			return "esc", 1
+		}
+		// Skip sequences for keys the browser doesn't use
+		if input[1] == '[' || input[1] == 'O' {
+			end := 2
+			for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+				end++
+			}
+			return "", min(end+1, len(input))
+		}
+		return "esc", 1
+	}
+
+	switch input[0] {
+	case '\r', '\n':
+		return "enter", 1
+	case 0x7f, 0x08:
+		return "backspace", 1
+	case 0x03:
+		return "ctrl-c", 1
+	case 0x0c:
+		return "ctrl-l", 1
+	}
+
+	r, size := utf8.DecodeRune(input)
+	if !unicode.IsPrint(r) {
+		return "", size
+	}
+	return string(r), size
+}
+
+// handleKey acts on a key press. done is set when the browser should close,
+// with ok telling whether selected holds a path to print.
+func (b *browser) handleKey(key string) (selected string, done, ok bool) {
+	b.message = ""
+
+	// Typing a filter, keys edit it except for the ones that move around
+	if b.filtering {
+		switch key {
+		case "up", "down", "pgup", "pgdn", "ctrl-c":
+			// handled below
+		case "enter":
+			b.filtering = false
+			return "", false, false
+		case "esc":
+			b.filtering = false
+			b.setFilter("")
+			return "", false, false
+		case "backspace":
+			if b.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(b.filter)
+				b.setFilter(b.filter[:len(b.filter)-size])
+			}
+			return "", false, false
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				b.setFilter(b.filter + key)
+			}
+			return "", false, false
+		}
+	}
+
+	page := max(b.listHeight()-1, 1)
+	switch key {
+	case "up", "k":
+		b.moveCursor(-1)
+	case "down", "j":
+		b.moveCursor(1)
+	case "pgup":
+		b.moveCursor(-page)
+	case "pgdn":
+		b.moveCursor(page)
+	case "home", "g":
+		b.moveCursor(-len(b.visible))
+	case "end", "G":
+		b.moveCursor(len(b.visible))
+	case "right", "l":
+		if file := b.current(); file != nil && isBrowsable(file) {
+			b.changeDir(b.currentPath(), "")
+		}
+	case "enter":
+		file := b.current()
+		if file == nil {
+			break
+		}
+		if isBrowsable(file) {
+			b.changeDir(b.currentPath(), "")
+			break
+		}
+		// Choosing a file ends the browser with its path
+		return b.currentPath(), true, true
+	case "left", "h", "backspace":
+		parent := filepath.Dir(b.dir)
+		if parent != b.dir {
+			b.changeDir(parent, filepath.Base(b.dir))
+		}
+	case "/":
+		b.filtering = true
+	case "esc":
+		b.setFilter("")
+	case ".":
+		b.flags.AlmostAll = !b.flags.AlmostAll
+		b.reload(b.currentName())
+	case "s":
+		b.sortIndex = (b.sortIndex + 1) % len(browserSorts)
+		b.flags.SortBy = browserSorts[b.sortIndex]
+		b.resort()
+	case "r":
+		b.flags.Reverse = !b.flags.Reverse
+		b.resort()
+	case "p":
+		b.preview = !b.preview
+	case "ctrl-l":
+		fmt.Fprint(b.tty, escClearScreen)
+	case "q":
+		// Quitting picks the directory being shown
+		return b.dir, true, true
+	case "Q", "ctrl-c":
+		return "", true, false
+	}
+	return "", false, false
+}
+
+// isBrowsable reports whether an entry can be entered: a directory, a link
+// to one, or an archive
+func isBrowsable(file os.FileInfo) bool {
+	if file.IsDir() || isListableArchive(entryPath(file), file) {
+		return true
+	}
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := statPath(entryPath(file))
+		return err == nil && target.IsDir()
+	}
+	return false
+}
+
+// changeDir shows another directory, putting the cursor on the entry
+// named select if there is one
+func (b *browser) changeDir(dir, selectName string) {
+	previous := b.dir
+	b.dir = dir
+	b.filter, b.filtering = "", false
+	if !b.reload(selectName) && previous != "" {
+		// Stay where we were if the new directory can't be read
+		b.dir = previous
+		message := b.message
+		b.reload(filepath.Base(dir))
+		b.message = message
+	}
+}
+
+// reload reads the directory again, keeping the cursor on selectName
+func (b *browser) reload(selectName string) bool {
+	entries, err := readDirectory(b.dir, b.flags)
+	if err != nil {
+		b.message = describeError(err)
+		return false
+	}
+	// --only-dirs, --larger-than and the like apply here as in a listing
+	b.entries = filterEntries(entries, b.flags)
+	b.applyFilter(selectName)
+	return true
+}
+
+// resort orders the entries after a change of sort, keeping the selection
+func (b *browser) resort() {
+	sortFiles(b.entries, b.flags)
+	b.applyFilter(b.currentName())
+}
+
+func (b *browser) setFilter(filter string) {
+	b.filter = filter
+	b.applyFilter(b.currentName())
+}
+
+// applyFilter keeps the entries whose names fuzzily match the filter and
+// moves the cursor to selectName, or the first entry if it's gone
+func (b *browser) applyFilter(selectName string) {
+	b.visible = b.visible[:0]
+	for _, file := range b.entries {
+		if fuzzyMatch(file.Name(), b.filter) {
+			b.visible = append(b.visible, file)
+		}
+	}
+
+	b.cursor, b.offset = 0, 0
+	for i, file := range b.visible {
+		if file.Name() == selectName {
+			b.cursor = i
+		}
+	}
+	b.moveCursor(0)
+}
+
+// fuzzyMatch reports whether the letters of pattern appear in name in
+// order, ignoring case, so "mfg" matches "main_flags.go"
+func fuzzyMatch(name, pattern string) bool {
+	name = strings.ToLower(name)
+	for _, r := range strings.ToLower(pattern) {
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// moveCursor moves the selection by delta entries, scrolling to keep it on screen
+func (b *browser) moveCursor(delta int) {
+	b.cursor = max(min(b.cursor+delta, len(b.visible)-1), 0)
+
+	height := b.listHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+height {
+		b.offset = b.cursor - height + 1
+	}
+}
+
+func (b *browser) current() os.FileInfo {
+	if b.cursor < len(b.visible) {
+		return b.visible[b.cursor]
+	}
+	return nil
+}
+
+func (b *browser) currentName() string {
+	if file := b.current(); file != nil {
+		return file.Name()
+	}
+	return ""
+}
+
+func (b *browser) currentPath() string {
+	return filepath.Join(b.dir, b.currentName())
+}
+
+// listHeight is the number of entries that fit between the header and status lines
+func (b *browser) listHeight() int {
+	return max(b.rows-2, 1)
+}
+
+// draw repaints the whole screen: the directory, the entries with the
+// preview pane beside them, and a status line
+func (b *browser) draw() {
+	b.rows, b.cols = 24, 80
+	if rows, cols, ok := getWindowSize(b.tty.Fd()); ok && rows > 0 && cols > 0 {
+		b.rows, b.cols = rows, cols
+	}
+	b.moveCursor(0)
+
+	listWidth, previewWidth := b.cols, 0
+	if b.preview && b.cols >= 60 {
+		listWidth = b.cols * 2 / 5
+		previewWidth = b.cols - listWidth - 1
+	}
+
+	var out strings.Builder
+	out.WriteString(escHome)
+
+	// Header: where we are and what's being filtered
+	header := b.dir
+	if b.filter != "" || b.filtering {
+		header += "  /" + b.filter
+	}
+	out.WriteString(escBold + truncateToWidth(escapeControls(header), b.cols) + escReset + escClearLine + "\r\n")
+
+	var preview []string
+	if previewWidth > 0 {
+		preview = b.previewLines(previewWidth, b.listHeight())
+	}
+
+	for row := 0; row < b.listHeight(); row++ {
+		i := b.offset + row
+		if i < len(b.visible) {
+			out.WriteString(renderBrowserName(b.visible[i], listWidth, i == b.cursor))
+		} else {
+			out.WriteString(spaces(listWidth))
+		}
+		if previewWidth > 0 {
+			out.WriteString(escDim + "│" + escReset)
+			if row < len(preview) {
+				out.WriteString(preview[row])
+			}
+		}
+		out.WriteString(escClearLine + "\r\n")
+	}
+
+	out.WriteString(escReverse + truncateToWidth(b.statusLine(), b.cols) + escClearLine + escReset)
+	fmt.Fprint(b.tty, out.String())
+}
+
+// statusLine shows the error of the last action, or the position, the
+// sort order and a reminder of the keys
+func (b *browser) statusLine() string {
+	if b.message != "" {
+		return " " + b.message
+	}
+	if b.filtering {
+		return " filter: type to narrow, enter to keep, esc to clear"
+	}
+
+	position := "empty"
+	if len(b.visible) > 0 {
+		position = fmt.Sprintf("%d/%d", b.cursor+1, len(b.visible))
+	}
+	sortBy := b.flags.SortBy
+	if b.flags.Reverse {
+		sortBy += ", reversed"
+	}
+	hidden := ""
+	if b.flags.AlmostAll {
+		hidden = ", hidden shown"
+	}
+	return fmt.Sprintf(" %s  sort: %s%s  |  hjkl/arrows move  / filter  . hidden  s sort  r reverse  p preview  q cd here  Q cancel",
+		position, sortBy, hidden)
+}
+
+// renderBrowserName draws an entry with its icon and color, cut and
+// padded to the width of the list. The selected one is shown in reverse
+// video, without colors so the highlight stays readable.
+func renderBrowserName(file os.FileInfo, width int, selected bool) string {
+	colorCode, icon := getEntryColorAndIcon(file)
+	name := escapeControls(file.Name())
+	if file.IsDir() {
+		name += "/"
+	}
+	text := " " + name
+	if iconsEnabled {
+		text = " " + icon + " " + name
+	}
+	text = truncateToWidth(text, width)
+	padding := spaces(width - displayWidth(text))
+
+	if selected {
+		return escReverse + text + padding + escReset
+	}
+	return colorCode + text + Color["reset"] + padding
+}
+
+// previewLines renders what the selected entry holds: the listing of a
+// directory or archive, the start of a text file, or a note for anything else
+func (b *browser) previewLines(width, height int) []string {
+	file := b.current()
+	if file == nil {
+		return nil
+	}
+	path := b.currentPath()
+
+	if isBrowsable(file) {
+		entries, err := readDirectory(path, b.flags)
+		if err != nil {
+			return []string{" " + truncateToWidth(describeError(err), width-1)}
+		}
+		entries = filterEntries(entries, b.flags)
+		if len(entries) == 0 {
+			return []string{escDim + " (empty)" + escReset}
+		}
+		var lines []string
+		for _, entry := range entries[:min(len(entries), height)] {
+			lines = append(lines, renderBrowserName(entry, width, false))
+		}
+		return lines
+	}
+
+	if !file.Mode().IsRegular() {
+		return []string{" " + truncateToWidth(describeFileType(file), width-1)}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{" " + truncateToWidth(describeError(err), width-1)}
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(io.LimitReader(f, previewBytes))
+
+	// Binary files get a one line description instead
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(trimPartialRune(data)) {
+		description := fmt.Sprintf("%s, %s", describeFileType(file), FormatFileSize(file.Size(), Flags{HumanReadable: true}))
+		return []string{" " + truncateToWidth(description, width-1)}
+	}
+
+	lines := strings.SplitN(string(data), "\n", height+1)
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for i, line := range lines {
+		lines[i] = " " + truncateToWidth(sanitizePreviewLine(line), width-1)
+	}
+	return lines
+}
+
+// trimPartialRune drops a character cut in half at the end of the preview
+func trimPartialRune(data []byte) []byte {
+	for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+		if utf8.Valid(data) {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// escapeControls shows control characters in a name as "?", like ls
+// does on a terminal, so a name can't send escape sequences or break lines
+func escapeControls(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '?'
+		}
+		return r
+	}, name)
+}
+
+// sanitizePreviewLine expands tabs and drops control characters, so a
+// file can't move the cursor or change colors on the screen
+func sanitizePreviewLine(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		switch {
+		case r == '\t':
+			b.WriteString("    ")
+		case unicode.IsControl(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,159 @@
+// json_output.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Version of the JSON entry schema, bumped whenever a field changes
+// meaning or is removed. Adding fields doesn't change the version.
+const jsonSchemaVersion = 1
+
+// jsonEntry is one listed file in --json and --ndjson output.
+// The fields are documented in the README under "JSON output".
+type jsonEntry struct {
+	SchemaVersion int    `json:"schema_version,omitempty"` // only set in --ndjson
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	Type          string `json:"type"`
+	Size          int64  `json:"size"`
+	DiskUsage     int64  `json:"disk_usage"`
+	Mode          string `json:"mode"`
+	Permissions   string `json:"permissions"`
+	Owner         string `json:"owner"`
+	Group         string `json:"group"`
+	Links         uint64 `json:"links"`
+	Mtime         string `json:"mtime"`
+	Atime         string `json:"atime,omitempty"`
+	Ctime         string `json:"ctime,omitempty"`
+	Btime         string `json:"btime,omitempty"`
+	Target        string `json:"target,omitempty"`
+	Git           string `json:"git,omitempty"` // only with --git
+	Icon          string `json:"icon"`
+	Color         string `json:"color"`
+}
+
+// jsonDocument is the top level object written by --json
+type jsonDocument struct {
+	SchemaVersion int         `json:"schema_version"`
+	Entries       []jsonEntry `json:"entries"`
+}
+
+// Entries collected for --json, written all at once by finishJSONOutput
+var jsonEntries = []jsonEntry{}
+
+// writeJSONEntries outputs a batch of listed files. With --ndjson they're
+// written right away, one object per line, otherwise they're collected.
+func writeJSONEntries(files []os.FileInfo, flags Flags) {
+	for _, file := range files {
+		entry := newJSONEntry(file)
+		if flags.Git {
+			entry.Git = gitStatusText(file)
+		}
+
+		if !flags.NDJSON {
+			jsonEntries = append(jsonEntries, entry)
+			continue
+		}
+
+		entry.SchemaVersion = jsonSchemaVersion
+		line, err := json.Marshal(entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lsx: %v\n", err)
+			continue
+		}
+		fmt.Println(string(line))
+	}
+}
+
+// finishJSONOutput writes the collected entries for --json
+func finishJSONOutput(flags Flags) {
+	if !flags.JSON || flags.NDJSON {
+		return
+	}
+
+	document := jsonDocument{SchemaVersion: jsonSchemaVersion, Entries: jsonEntries}
+	output, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lsx: %v\n", err)
+		return
+	}
+	fmt.Println(string(output))
+}
+
+// builds the JSON description of a file
+func newJSONEntry(file os.FileInfo) jsonEntry {
+	owner, group := getFileOwner(file)
+	colorKey, iconKey := getEntryStyle(file)
+
+	entry := jsonEntry{
+		Name:        file.Name(),
+		Path:        entryPath(file),
+		Type:        fileTypeName(file.Mode()),
+		Size:        file.Size(),
+		DiskUsage:   entryDiskUsage(file),
+		Mode:        fmt.Sprintf("%04o", unixMode(file.Mode())),
+		Permissions: FormatPermissions(file),
+		Owner:       owner,
+		Group:       group,
+		Links:       getLinkCount(file),
+		Mtime:       file.ModTime().Format(time.RFC3339),
+		Icon:        iconKey,
+		Color:       colorKey,
+	}
+
+	if atime, ctime, ok := getFileTimes(file); ok {
+		entry.Atime = atime.Format(time.RFC3339)
+		entry.Ctime = ctime.Format(time.RFC3339)
+	}
+	if btime, ok := getBirthTime(file); ok {
+		entry.Btime = btime.Format(time.RFC3339)
+	}
+
+	if file.Mode()&os.ModeSymlink != 0 {
+		if target, err := readLinkPath(entryPath(file)); err == nil {
+			entry.Target = target
+		}
+	}
+	return entry
+}
+
+// fileTypeName names the type of a file for the "type" field
+func fileTypeName(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "char_device"
+	case mode&os.ModeDevice != 0:
+		return "block_device"
+	default:
+		return "file"
+	}
+}
+
+// unixMode converts Go's file mode into the permission bits of st_mode,
+// including the setuid, setgid and sticky bits
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
@@ -9,6 +9,7 @@ var Icons = map[string]string {
 	"folder":        "\uf07b",
 	"open_folder":   "\uf07c",
 	"git_folder":    "\uf1d3",
+	"sticky_folder": "\uf114",
 
 	// Framework Specific
 	"tailwind":                  "\ue8ba", // Tailwindcss
@@ -130,8 +131,20 @@ var Icons = map[string]string {
 	".gitignore":       "\ue65d",
 	".gitattributes":   "\ue65d",
 
+	// Symbolic links
+	"symlink":     "\uf481", // Link to a file
+	"symlink_dir": "\uf482", // Link to a directory
+	"broken_link": "\uf127", // Link whose target is missing
+
+	// Special files
+	"pipe":         "\uf0ec", // Named pipe
+	"socket":       "\uf1e6", // Socket
+	"char_device":  "\uf2db", // Character device
+	"block_device": "\uf0a0", // Block device
+
 	// Executable
 	"executable": "\ueae8", // Binary
+	"setuid":     "\uf132", // Runs as its owner or group
 	"dockerfile": "\ue7b0", // Dockerfile
 	"makefile":   "\ue70e", // makefile
 	"cmake":      "\ue794", // CMakeFile
@@ -3,120 +3,230 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-// printLongFormat displays files in the long listing format like ls -l
-func printLongFormat(files []os.FileInfo, humanReadable bool) {
-	// Calculate total size in 1K blocks
-	var totalSize int64
-	for _, file := range files {
-		totalSize += file.Size()
-	}
-	totalBlocks := (totalSize + 1023) / 1024
-
-	// Print total line (Unix ls compatibility)
-	if humanReadable {
-		fmt.Printf("total %s\n", FormatFileSize(totalBlocks*1024, true))
-	} else {
-		fmt.Printf("total %d\n", totalBlocks)
-	}
-
-	// Find the maximum length for various fields to ensure alignment
-	maxSizeLen := 0
-	maxOwnerLen := 0
-	maxGroupLen := 0
+// A column of the long format
+type longColumn struct {
+	rightAlign bool // numbers line up on the right
+	value      func(file os.FileInfo, flags Flags) string
+}
 
-	// First pass to determine max field lengths
-	for _, file := range files {
-		// Size length
-		size := file.Size()
-		sizeStr := ""
-		if humanReadable {
-			sizeStr = FormatFileSize(size, true)
-		} else {
-			sizeStr = strconv.FormatInt(size, 10)
+// Columns that --columns can show
+var longColumns = map[string]longColumn{
+	"inode": {true, func(file os.FileInfo, flags Flags) string {
+		if id, ok := getFileID(file); ok {
+			return strconv.FormatUint(id.ino, 10)
 		}
-		if len(sizeStr) > maxSizeLen {
-			maxSizeLen = len(sizeStr)
+		return "?"
+	}},
+	"blocks": {true, func(file os.FileInfo, flags Flags) string {
+		return FormatBlocks(entryDiskUsage(file), flags)
+	}},
+	"perms": {false, func(file os.FileInfo, flags Flags) string {
+		return FormatPermissions(file) + accessMarker(file)
+	}},
+	"octal": {false, func(file os.FileInfo, flags Flags) string {
+		return fmt.Sprintf("%04o", unixMode(file.Mode()))
+	}},
+	"links": {true, func(file os.FileInfo, flags Flags) string {
+		return strconv.FormatUint(getLinkCount(file), 10)
+	}},
+	"owner": {false, func(file os.FileInfo, flags Flags) string {
+		owner, _ := getFileOwner(file)
+		return owner
+	}},
+	"group": {false, func(file os.FileInfo, flags Flags) string {
+		_, group := getFileOwner(file)
+		return group
+	}},
+	"size": {true, func(file os.FileInfo, flags Flags) string {
+		// Devices have no size, ls shows their device numbers instead
+		if major, minor, ok := getDeviceNumbers(file); ok {
+			return fmt.Sprintf("%3d, %3d", major, minor)
 		}
+		return FormatFileSize(file.Size(), flags)
+	}},
+	"time": {false, func(file os.FileInfo, flags Flags) string {
+		return formatFileTime(file, flags)
+	}},
+	"context": {false, func(file os.FileInfo, flags Flags) string {
+		return securityContext(file)
+	}},
+	"git": {false, func(file os.FileInfo, flags Flags) string {
+		return gitBadge(file)
+	}},
+	"type": {false, func(file os.FileInfo, flags Flags) string {
+		return describeFileType(file)
+	}},
+	"name": {false, func(file os.FileInfo, flags Flags) string {
+		// Colored icon and name, followed by the target for symlinks
+		name, _ := formatEntryName(file, flags)
+		return name + formatLinkTarget(file, flags)
+	}},
+}
 
-		// Get owner and group and find max lengths
-		filePath := file.Name()
-		owner, group := getFileOwner(filePath)
-		if len(owner) > maxOwnerLen {
-			maxOwnerLen = len(owner)
-		}
-		if len(group) > maxGroupLen {
-			maxGroupLen = len(group)
+// The columns of ls -l, when --columns isn't given
+var defaultLongColumns = []string{"perms", "links", "owner", "group", "size", "time", "name"}
+
+// parseColumns reads a comma separated --columns list
+func parseColumns(value string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := longColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column '%s'", name)
 		}
+		columns = append(columns, name)
 	}
+	return columns, nil
+}
 
-	// Now print each file in Unix-like format
-	for _, file := range files {
-		name := file.Name()
-		mode := file.Mode()
+// longFormatColumns returns the columns to show: --columns or the ls ones,
+// plus those asked for by -i, -s, -Z and --git if they aren't there already
+func longFormatColumns(flags Flags) []string {
+	columns := defaultLongColumns
+	if len(flags.Columns) > 0 {
+		columns = flags.Columns
+	}
+
+	var extra []string
+	if flags.Inode && !isInList("inode", columns) {
+		extra = append(extra, "inode")
+	}
+	if flags.ShowBlocks && !isInList("blocks", columns) {
+		extra = append(extra, "blocks")
+	}
+	columns = append(extra, columns...)
+
+	// Like ls -Z, the context follows the group
+	if flags.Context && !isInList("context", columns) {
+		columns = insertColumn(columns, "context", "group", 1)
+	}
 
-		// Default Unix-style permission format
-		perms := "-"
+	// The git status goes right before the name
+	if flags.Git && !isInList("git", columns) {
+		columns = insertColumn(columns, "git", "name", 0)
+	}
+	return columns
+}
 
-		// File type
-		if mode.IsDir() {
-			perms = "d"
+// insertColumn adds a column next to another, offset 0 putting it before
+// and 1 after that one; it goes at the end when the other isn't shown
+func insertColumn(columns []string, column, next string, offset int) []string {
+	at := len(columns)
+	for i, name := range columns {
+		if name == next {
+			at = i + offset
 		}
+	}
+	return append(columns[:at:at], append([]string{column}, columns[at:]...)...)
+}
+
+// gridPrefixColumns returns the columns that -i, -s and -Z put in front of
+// names outside the long format
+func gridPrefixColumns(flags Flags) []string {
+	var columns []string
+	if flags.Inode {
+		columns = append(columns, "inode")
+	}
+	if flags.ShowBlocks {
+		columns = append(columns, "blocks")
+	}
+	if flags.Context {
+		columns = append(columns, "context")
+	}
+	return columns
+}
 
-		// Add simulated rwx permissions
-		if mode.IsDir() {
-			perms += "rwxr-xr-x" // directories
-		} else if strings.HasSuffix(strings.ToLower(name), ".exe") {
-			perms += "rwxr-xr--" // executables
-		} else {
-			perms += "rw-r--r--" // regular files
+// formatColumns renders the given columns for every file, each padded to
+// the widest value in its column, and joins them with single spaces
+func formatColumns(files []os.FileInfo, columns []string, flags Flags) []string {
+	cells := make([][]string, len(files))
+	widths := make([]int, len(columns))
+	for i, file := range files {
+		cells[i] = make([]string, len(columns))
+		for j, name := range columns {
+			cells[i][j] = longColumns[name].value(file, flags)
+			widths[j] = max(widths[j], visibleWidth(cells[i][j]))
 		}
+	}
 
-		// Get links, owner and group
-		links := 1
-		if mode.IsDir() {
-			links = 2 // Dirs often have 2+ links in Unix
+	lines := make([]string, len(files))
+	for i := range files {
+		var line strings.Builder
+		for j, name := range columns {
+			if j > 0 {
+				line.WriteByte(' ')
+			}
+			padding := spaces(widths[j] - visibleWidth(cells[i][j]))
+			switch {
+			case longColumns[name].rightAlign:
+				line.WriteString(padding + cells[i][j])
+			case j == len(columns)-1:
+				// Nothing to line up after the last column
+				line.WriteString(cells[i][j])
+			default:
+				line.WriteString(cells[i][j] + padding)
+			}
 		}
+		lines[i] = line.String()
+	}
+	return lines
+}
 
-		owner, group := getFileOwner(name)
+// printLongFormat displays files in the long listing format like ls -l
+func printLongFormat(files []os.FileInfo, flags Flags, showTotal bool) {
+	// Print total line (Unix ls compatibility), only for directory contents
+	if showTotal {
+		fmt.Printf("total %s\n", FormatBlocks(calculateTotalBlocks(files), flags))
+	}
+
+	for i, line := range formatColumns(files, longFormatColumns(flags), flags) {
+		fmt.Println(line)
 
-		// Format size
-		size := file.Size()
-		sizeStr := ""
-		if humanReadable {
-			sizeStr = FormatFileSize(size, true)
-		} else {
-			sizeStr = strconv.FormatInt(size, 10)
+		// -@ lists extended attributes under each entry
+		if flags.Xattrs {
+			printXattrs(files[i])
 		}
+	}
+}
 
-		// Format modification time
-		modTime := FormatModTime(file.ModTime())
+// calculateTotalBlocks adds up the space allocated to files on disk, in
+// bytes, from the stat block counts; FormatBlocks turns it into 1K blocks
+func calculateTotalBlocks(files []os.FileInfo) int64 {
+	var total int64
+	for _, file := range files {
+		total += getAllocatedSize(file)
+	}
+	return total
+}
 
-		// Get color and icon for file
-		ext := strings.ToLower(filepath.Ext(name))
-		isDir := file.IsDir()
-		colorCode, icon := getFileTypeColorAndIcon(file, ext, isDir)
+// describeFileType names the type of a file the way stat(1) does
+func describeFileType(file os.FileInfo) string {
+	mode := file.Mode()
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symbolic link"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character special file"
+	case mode&os.ModeDevice != 0:
+		return "block special file"
+	case file.Size() == 0:
+		return "regular empty file"
+	}
 
-		// Format display name
-		displayName := name
-		if isDir {
-			displayName += "/"
+	if detectEnabled {
+		if detected := detectFileType(file); detected != nil {
+			return detected.description
 		}
-
-		// Print in Unix-like format without extra newlines
-		fmt.Printf("%s %2d %-*s %-*s %*s %s %s%s %s%s%s\n",
-			perms,
-			links,
-			maxOwnerLen, owner,
-			maxGroupLen, group,
-			maxSizeLen, sizeStr,
-			modTime,
-			colorCode, icon,
-			Color["white"], displayName, Color["reset"],
-		)
 	}
+	return "regular file"
 }
@@ -0,0 +1,242 @@
+// ls_colors.go
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LSColors holds colors parsed from LS_COLORS or a dircolors file,
+// already turned into ANSI escape sequences
+type LSColors struct {
+	types      map[string]string // two letter keys like "di" or "ln"
+	extensions map[string]string // lowercase suffixes from "*.ext" keys
+}
+
+// Colors from LS_COLORS and --dircolors, overriding the built-in palette.
+// Empty when neither is set.
+var lsColors = newLSColors()
+
+// Value of "ln" that colors links by the type of their target
+const linkTargetColor = "target"
+
+// Keywords of a dircolors file and the LS_COLORS keys they set
+var dircolorsKeywords = map[string]string{
+	"NORMAL":                "no",
+	"FILE":                  "fi",
+	"RESET":                 "rs",
+	"DIR":                   "di",
+	"LINK":                  "ln",
+	"SYMLINK":               "ln",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"DOOR":                  "do",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"EXEC":                  "ex",
+	"SETUID":                "su",
+	"SETGID":                "sg",
+	"CAPABILITY":            "ca",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OTHER_WRITABLE":        "ow",
+	"STICKY":                "st",
+	"MULTIHARDLINK":         "mh",
+}
+
+func newLSColors() *LSColors {
+	return &LSColors{
+		types:      map[string]string{},
+		extensions: map[string]string{},
+	}
+}
+
+// ParseLSColors reads a LS_COLORS value like "di=01;34:*.go=36" into c.
+// Entries without "=" are ignored, as ls does.
+func (c *LSColors) ParseLSColors(value string) {
+	for _, item := range strings.Split(value, ":") {
+		key, code, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			continue
+		}
+		c.set(key, code)
+	}
+}
+
+// LoadDircolors reads a dircolors database file into c
+func (c *LSColors) LoadDircolors(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+
+		// Strip comments and surrounding whitespace
+		fields := strings.Fields(stripDircolorsComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: invalid line; expected KEYWORD VALUE", path, lineNum)
+		}
+
+		keyword, code := fields[0], fields[1]
+		switch {
+		case strings.EqualFold(keyword, "TERM"), strings.EqualFold(keyword, "COLORTERM"),
+			strings.EqualFold(keyword, "COLOR"), strings.EqualFold(keyword, "OPTIONS"),
+			strings.EqualFold(keyword, "EIGHTBIT"):
+			// Terminal selection and options don't affect colors
+		case strings.HasPrefix(keyword, "."):
+			c.set("*"+keyword, code)
+		case strings.HasPrefix(keyword, "*"):
+			c.set(keyword, code)
+		default:
+			key, ok := dircolorsKeywords[strings.ToUpper(keyword)]
+			if !ok {
+				return fmt.Errorf("%s:%d: unrecognized keyword %s", path, lineNum, keyword)
+			}
+			c.set(key, code)
+		}
+	}
+	return scanner.Err()
+}
+
+// stripDircolorsComment cuts a comment off a dircolors line. Like
+// dircolors, "#" only starts one at the start of the line or after
+// whitespace, so patterns like "*#" keep theirs.
+func stripDircolorsComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// stores one key, turning the SGR code into an escape sequence
+func (c *LSColors) set(key, code string) {
+	escape := "\x1b[" + code + "m"
+	if code == linkTargetColor {
+		escape = code
+	}
+	if suffix, ok := strings.CutPrefix(key, "*"); ok {
+		c.extensions[strings.ToLower(suffix)] = escape
+		return
+	}
+	c.types[key] = escape
+}
+
+// ColorFor returns the color LS_COLORS assigns to an entry, if any
+func (c *LSColors) ColorFor(file os.FileInfo) (string, bool) {
+	if len(c.types) == 0 && len(c.extensions) == 0 {
+		return "", false
+	}
+
+	mode := file.Mode()
+	switch {
+	case mode.IsDir():
+		otherWritable := mode.Perm()&0002 != 0
+		sticky := mode&os.ModeSticky != 0
+		switch {
+		case otherWritable && sticky:
+			return c.firstOf("tw", "ow", "st", "di")
+		case otherWritable:
+			return c.firstOf("ow", "di")
+		case sticky:
+			return c.firstOf("st", "di")
+		}
+		return c.firstOf("di")
+
+	case mode&os.ModeSymlink != 0:
+		target, err := statPath(entryPath(file))
+		if err != nil {
+			if color, ok := c.firstOf("or"); ok {
+				return color, true
+			}
+		}
+
+		// "ln=target" colors the link like the file it points to
+		color, ok := c.firstOf("ln")
+		if color == linkTargetColor {
+			if err != nil {
+				return "", false
+			}
+			return c.ColorFor(newFileEntry(target, entryPath(file)))
+		}
+		return color, ok
+
+	case mode&os.ModeNamedPipe != 0:
+		return c.firstOf("pi")
+	case mode&os.ModeSocket != 0:
+		return c.firstOf("so")
+	case mode&os.ModeDevice != 0 && mode&os.ModeCharDevice != 0:
+		return c.firstOf("cd")
+	case mode&os.ModeDevice != 0:
+		return c.firstOf("bd")
+	}
+
+	// Regular files: special permission bits, then executables, then suffixes
+	if mode&os.ModeSetuid != 0 {
+		if color, ok := c.firstOf("su"); ok {
+			return color, true
+		}
+	}
+	if mode&os.ModeSetgid != 0 {
+		if color, ok := c.firstOf("sg"); ok {
+			return color, true
+		}
+	}
+	if mode&0111 != 0 {
+		if color, ok := c.firstOf("ex"); ok {
+			return color, true
+		}
+	}
+	return c.suffixColor(file.Name())
+}
+
+// returns the color of the first key that is set
+func (c *LSColors) firstOf(keys ...string) (string, bool) {
+	for _, key := range keys {
+		if color, ok := c.types[key]; ok {
+			return color, true
+		}
+	}
+	return "", false
+}
+
+// finds the longest "*suffix" key matching a file name
+func (c *LSColors) suffixColor(name string) (string, bool) {
+	name = strings.ToLower(name)
+	best := ""
+	color := ""
+	for suffix, escape := range c.extensions {
+		if len(suffix) > len(best) && strings.HasSuffix(name, suffix) {
+			best, color = suffix, escape
+		}
+	}
+	return color, best != ""
+}
+
+// setupLSColors loads LS_COLORS and the --dircolors file, if given
+func setupLSColors(flags Flags) {
+	lsColors.ParseLSColors(os.Getenv("LS_COLORS"))
+
+	if flags.DircolorsFile != "" {
+		if err := lsColors.LoadDircolors(flags.DircolorsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "lsx: %v\n", err)
+			os.Exit(2)
+		}
+	}
+}
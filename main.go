@@ -3,10 +3,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"strings"
+	"syscall"
 )
 
 func main() {
@@ -16,117 +17,201 @@ func main() {
 	// Handle flags
 	flags, remainingArgs := handle_flag(args)
 
-	// Determine the path to process - use the first non-flag argument if it exists
-	// otherwise use the current directory
-	var path string
-	if len(remainingArgs) > 1 {
-		path = remainingArgs[1]
-	} else {
-		path = "."
-	}
-
-	// Checking if the provided path is a directory
-	fileInfo, err := os.Stat(path)
-	if err == nil && fileInfo.IsDir() {
-		// If -d flag is set, print the directory entry itself, not its contents
-		if flags.DirectoryOnly {
-			fileInfos := []os.FileInfo{fileInfo}
-			PrintFilesInColumns(fileInfos, 1)
-		} else {
-			// It's a directory, print its contents
-			printDirectoryContents(path, flags)
+	// List every path given, or the current directory if there are none
+	paths := remainingArgs[1:]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// --interactive browses from the first path instead of listing
+	if flags.Interactive {
+		runInteractive(paths[0], flags)
+		os.Exit(exitStatus)
+	}
+
+	listPaths(paths, flags)
+
+	finishJSONOutput(flags)
+	os.Exit(exitStatus)
+}
+
+// listPaths lists the command line arguments like ls: files first, all
+// together, then each directory's contents under its own header
+func listPaths(args []string, flags Flags) {
+	files := make([]os.FileInfo, 0)
+	dirs := make([]os.FileInfo, 0)
+
+	for _, arg := range args {
+		// Glob patterns the shell didn't expand (because they were quoted)
+		// are expanded here, unless a file has that very name
+		matches := []string{arg}
+		if needsExpansion(arg) {
+			matches = expandGlob(arg, flags)
+			if len(matches) == 0 {
+				reportError(exitTrouble, "cannot access '%s': No such file or directory", arg)
+				continue
+			}
+		}
+
+		for _, path := range matches {
+			info, err := statArgument(path, flags)
+			if err != nil {
+				reportError(exitTrouble, "cannot access '%s': %s", path, describeError(err))
+				continue
+			}
+
+			// Archives are listed like the directories they pack up
+			if !flags.DirectoryOnly && isListableArchive(path, info) {
+				info = archiveRootInfo(info)
+			}
+
+			// If -d flag is set, list the directory entry itself, not its contents
+			entry := newArgumentEntry(info, path)
+			if info.IsDir() && !flags.DirectoryOnly {
+				dirs = append(dirs, entry)
+			} else {
+				files = append(files, entry)
+			}
 		}
-	} else {
-		// It's not a directory or there's an error, handle as pattern
-		processPath(path, flags)
+	}
+
+	applyTotalSizes(files, flags)
+	sortFiles(files, flags)
+	sortFiles(dirs, flags)
+	PrintFilesInColumns(files, flags, false)
+
+	// Headers are needed as soon as there's more than one thing listed
+	showHeaders := len(files)+len(dirs) > 1 || exitStatus != exitOK
+	for i, dir := range dirs {
+		if !flags.JSON && (i > 0 || len(files) > 0) {
+			fmt.Println()
+		}
+		printDirectoryContents(entryPath(dir), flags, showHeaders)
 	}
 }
 
-func processPath(pattern string, flags Flags) {
-	// 1. Handle `*.extension`
-	if strings.HasPrefix(pattern, "*.") {
-		ext := strings.TrimPrefix(pattern, "*")
-		printFilesWithExtension(".", ext, flags)
-		return
+// statArgument looks up a command line argument. Like ls, symlinks are
+// followed with -H or -L, and otherwise unless -l, -d or -F is given.
+// A broken link is still listed itself.
+func statArgument(path string, flags Flags) (os.FileInfo, error) {
+	follow := flags.Dereference || flags.DerefArgs ||
+		!(flags.LongFormat || flags.DirectoryOnly || flags.Classify)
+	if !follow {
+		return lstatPath(path)
 	}
 
-	// 2. Handle `<some_path>/*.extension`
-	parts := strings.Split(pattern, "/")
-	if len(parts) > 1 && strings.HasSuffix(parts[len(parts)-1], ".*") {
-		path := strings.Join(parts[:len(parts)-1], "/")
-		ext := strings.TrimPrefix(parts[len(parts)-1], "*")
-		printFilesWithExtension(path, ext, flags)
+	info, err := statPath(path)
+	if err != nil {
+		if linkInfo, linkErr := lstatPath(path); linkErr == nil {
+			return linkInfo, nil
+		}
+	}
+	return info, err
+}
+
+// printDirectoryContents lists a directory named on the command line,
+// under a "path:" header if asked to
+func printDirectoryContents(dirPath string, flags Flags, header bool) {
+	// --tree draws the whole hierarchy with branches, in JSON it lists
+	// the same entries as -R
+	if flags.Tree && !flags.JSON {
+		printTree(dirPath, flags)
 		return
 	}
 
-	// 3. Handle `<some_path>/<some_filename>` or `<some_filename>`
-	// Check if the file exists
-	_, err := os.Stat(pattern)
-	if err == nil {
-		printFile(pattern, flags)
+	// -R walks the whole tree, printing a header for each directory
+	if flags.Recursive || flags.Tree {
+		printDirectoryRecursive(dirPath, flags)
 		return
-	} else if os.IsNotExist(err) {
-		fmt.Println("Error: No such file or directory:", pattern)
+	}
+
+	if header && !flags.JSON {
+		fmt.Printf("%s:\n", dirPath)
+	}
+
+	fileInfos, err := readDirectory(dirPath, flags)
+	if err != nil {
+		reportError(exitTrouble, "cannot open directory '%s': %s", dirPath, describeError(err))
 		return
-	} else {
-		log.Fatal(err)
 	}
 
-	fmt.Println("Error: No such file or directory:", pattern)
+	PrintFilesInColumns(filterEntries(fileInfos, flags), flags, true)
 }
 
-func printFilesWithExtension(dirPath string, ext string, flags Flags) {
-	dirEntries, err := os.ReadDir(dirPath)
+// readDirectory reads the entries of a directory, leaving out the ones
+// pruned by the listing filters. With -a it also includes . and ..
+func readDirectory(dirPath string, flags Flags) ([]os.FileInfo, error) {
+	dirEntries, err := readDirPath(dirPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	fileInfos := make([]os.FileInfo, 0)
+
+	// -a lists the directory itself and its parent, except in the tree
+	if flags.AllFiles && !flags.Tree {
+		for _, name := range []string{".", ".."} {
+			path := joinPath(dirPath, name)
+			if info, err := statPath(path); err == nil && !isPruned(name, path, true, flags) {
+				fileInfos = append(fileInfos, newNamedEntry(info, path, name))
+			}
+		}
+	}
+
 	for _, entry := range dirEntries {
-		if !strings.HasSuffix(entry.Name(), ext) {
+		path := joinPath(dirPath, entry.Name())
+		if isPruned(entry.Name(), path, entry.IsDir(), flags) {
 			continue
 		}
+
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		fileInfos = append(fileInfos, info)
+		fileInfos = append(fileInfos, newFileEntry(followLink(info, path, flags), path))
 	}
-	PrintFilesInColumns(fileInfos, 5, flags)
+
+	applyTotalSizes(fileInfos, flags)
+	sortFiles(fileInfos, flags)
+	return fileInfos, nil
 }
 
-func printFile(filePath string, flags Flags) {
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	PrintFilesInColumns([]os.FileInfo{fileInfo}, 1, flags)
+// isDotDirectory reports whether an entry is the . or .. listed by -a
+func isDotDirectory(file os.FileInfo) bool {
+	return file.Name() == "." || file.Name() == ".."
 }
 
-func printDirectoryContents(dirPath string, flags Flags) {
-	dirEntries, err := os.ReadDir(dirPath)
-	if err != nil {
-		log.Fatal(err)
+// describeError turns an error from the os package into the message ls
+// would show, like "No such file or directory"
+func describeError(err error) string {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
 	}
+	message := err.Error()
 
-	fileInfos := make([]os.FileInfo, 0)
-	for _, entry := range dirEntries {
-		// Skip dotfiles - unless -a flag is set
-		if strings.HasPrefix(entry.Name(), ".") && !flags.AllFiles {
-			continue
-		}
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-		fileInfos = append(fileInfos, info)
+	// Only system errors are capitalized like strerror, messages such as
+	// "zip: not a valid zip file" keep the package name as it is
+	var errno syscall.Errno
+	if !errors.As(err, &errno) || message == "" {
+		return message
 	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
 
-	// For long format, use only 1 column
-	columns := 5
-	if flags.LongFormat {
-		columns = 1
-	}
+// Exit codes, the same as coreutils ls
+const (
+	exitOK      = 0 // success
+	exitMinor   = 1 // minor problems, like a subdirectory that can't be read
+	exitTrouble = 2 // serious trouble, like an argument that doesn't exist
+)
 
-	PrintFilesInColumns(fileInfos, columns, flags)
+// The status lsx exits with, raised by reportError
+var exitStatus = exitOK
+
+// reportError prints an error to stderr and raises the exit status
+func reportError(status int, format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "lsx: "+format+"\n", args...)
+	if status > exitStatus {
+		exitStatus = status
+	}
 }
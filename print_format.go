@@ -4,11 +4,9 @@ package main
 
 import (
 	"fmt"
-	"math"
 	"os"
 	"path/filepath"
 	"strings"
-	"unicode/utf8"
 )
 
 // check for a framework config file
@@ -29,168 +27,176 @@ func isConfigFile(name string) string {
 	}
 }
 
+// getEntryStyle picks the color and icon keys for a listed entry, taking
+// framework config files and git dotfiles into account
+func getEntryStyle(file os.FileInfo) (string, string) {
+	name := file.Name()
+	ext := strings.ToLower(filepath.Ext(name))
+	isDir := file.IsDir()
+
+	// Check for framework config files
+	var colorKey, iconKey string
+	if frameworkIcon := isConfigFile(name); frameworkIcon != "" {
+		colorKey, iconKey = "cyan", frameworkIcon // color for framework icons
+	} else {
+		// Get icon and color based on file type
+		colorKey, iconKey = getFileTypeColorAndIcon(file, ext, isDir)
+	}
+
+	// --detect lets the contents of a file overrule its name
+	if detectEnabled {
+		if detected := detectFileType(file); detected != nil {
+			colorKey, iconKey = detected.colorKey, detected.iconKey
+			if file.Mode()&0111 != 0 {
+				colorKey = "bright_green"
+			}
+		}
+	}
+
+	// Handle ".git" directory specifically
+	if isDir && name == ".git" {
+		iconKey = "git_folder"
+	}
+
+	// Handle .gitignore and .gitattributes as dotfiles
+	if name == ".gitignore" || name == ".gitattributes" {
+		iconKey = name
+	}
+
+	return colorKey, iconKey
+}
+
+// getEntryColorAndIcon returns the color escape and icon glyph for an entry,
+// applying the overrides from LS_COLORS and the config file
+func getEntryColorAndIcon(file os.FileInfo) (string, string) {
+	name := file.Name()
+	isDir := file.IsDir()
+
+	colorKey, iconKey := getEntryStyle(file)
+	colorCode, icon := resolveColor(colorKey), Icons[iconKey]
+
+	// Icons from the config file, by exact name
+	if customIcon, ok := fileNameIcons[name]; ok && !isDir {
+		icon = customIcon
+	}
+	if customIcon, ok := directoryIcons[name]; ok && isDir {
+		icon = customIcon
+	}
+
+	// LS_COLORS and --dircolors override the built-in palette,
+	// and colors from the config file override both
+	if color, ok := lsColors.ColorFor(file); ok {
+		colorCode = color
+	}
+	if color, ok := getConfigColor(file); ok {
+		colorCode = color
+	}
+
+	if !colorsEnabled {
+		colorCode = ""
+	}
+	return colorCode, icon
+}
+
+// formatEntryName renders an entry as its colored icon and name, with "/"
+// after directories (and the other -F indicators if asked for), and
+// returns the display width of the result
+func formatEntryName(file os.FileInfo, flags Flags) (string, int) {
+	colorCode, icon := getEntryColorAndIcon(file)
+	name := entryLabel(file)
+
+	// Add "/" for directories
+	if file.IsDir() && !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+
+	// -F marks the other file types too, except for links in long format
+	// since their target is shown instead
+	if flags.Classify && !(flags.LongFormat && file.Mode()&os.ModeSymlink != 0) {
+		name += classifyIndicator(file.Mode())
+	}
+
+	// Without icons the name itself carries the color
+	if !iconsEnabled {
+		return colorCode + name + Color["reset"], displayWidth(name)
+	}
+
+	// File display with colored icon and white filename
+	display := fmt.Sprintf("%s%s %s%s",
+		colorCode,
+		icon,
+		Color["white"]+name,
+		Color["reset"],
+	)
+	return display, displayWidth(icon) + 1 + displayWidth(name)
+}
+
 // display file information with icons and type-based colors
-func PrintFilesInColumns(files []os.FileInfo, numColumns int, flags ...Flags) {
+// showTotal is false for the files named on the command line, which
+// like in ls get no "total" line.
+func PrintFilesInColumns(files []os.FileInfo, flags Flags, showTotal bool) {
 	if len(files) == 0 {
 		return
 	}
 
-	// Check if flag info is available
-	var flagsInfo Flags
-	if len(flags) > 0 {
-		flagsInfo = flags[0]
+	// JSON output replaces every other layout
+	if flags.JSON {
+		writeJSONEntries(files, flags)
+		return
 	}
 
 	// If using long format, handle differently
-	if flagsInfo.LongFormat {
-		printLongFormat(files, flagsInfo.HumanReadable)
+	if flags.LongFormat {
+		printLongFormat(files, flags, showTotal)
 		return
 	}
 
-	// Calculate how many rows we need
-	numRows := int(math.Ceil(float64(len(files)) / float64(numColumns)))
-
-	// Find the maximum filename width needed for each column, respecting maxFilenameWidth
-	columnWidths := make([]int, numColumns)
-	for col := 0; col < numColumns; col++ {
-		for row := 0; row < numRows; row++ {
-			idx := row + col*numRows
-			if idx < len(files) {
-				nameWidth := utf8.RuneCountInString(files[idx].Name())
-				if nameWidth > maxFilenameWidth {
-					nameWidth = maxFilenameWidth
-				}
-				if nameWidth > columnWidths[col] {
-					columnWidths[col] = nameWidth
-				}
-			}
-		}
+	// -s shows the total first, like the long format does
+	if flags.ShowBlocks && showTotal {
+		fmt.Printf("total %s\n", FormatBlocks(calculateTotalBlocks(files), flags))
 	}
 
-	// Print files in rows with icons and colors
-	for row := 0; row < numRows; row++ {
-		// Look for long filenames
-		hasLongName := false
-		mainContent := make([]string, numColumns)
-		overflowContent := make([]string, numColumns)
-		iconColors := make([]string, numColumns)
-		icons := make([]string, numColumns)
-
-		for col := 0; col < numColumns; col++ {
-			idx := row + col*numRows
-			if idx < len(files) {
-				file := files[idx]
-				name := file.Name()
-				ext := strings.ToLower(filepath.Ext(name))
-				isDir := file.IsDir()
-
-				// Check for framework config files
-				frameworkIcon := isConfigFile(name)
-				if frameworkIcon != "" {
-					iconColors[col] = Color["cyan"] // color for framework icons
-					icons[col] = Icons[frameworkIcon]
-				} else {
-					// Get icon and color based on file type
-					colorCode, icon := getFileTypeColorAndIcon(file, ext, isDir)
-					iconColors[col] = colorCode
-					icons[col] = icon
-				}
-
-				// Handle ".git" directory specifically
-				if isDir && name == ".git" {
-					icons[col] = Icons["git_folder"]
-				}
-
-				// Handle .gitignore and .gitattributes as dotfiles
-				if name == ".gitignore" || name == ".gitattributes" {
-					icons[col] = Icons[name]
-				}
-
-				// Handle long filenames
-				if utf8.RuneCountInString(name) > maxFilenameWidth {
-					hasLongName = true
-					displayPart := truncateString(name, maxFilenameWidth)
-					overflowPart := name[len(displayPart):]
-
-					mainContent[col] = displayPart
-					overflowContent[col] = overflowPart
-				} else {
-					mainContent[col] = name
-					overflowContent[col] = ""
-				}
-			}
+	// -i and -s put the inode and allocated size in front of names
+	var prefixes []string
+	if columns := gridPrefixColumns(flags); len(columns) > 0 {
+		prefixes = formatColumns(files, columns, flags)
+	}
+
+	// Render every entry as a colored icon followed by its name
+	cells := make([]string, len(files))
+	widths := make([]int, len(files))
+	for i, file := range files {
+		cells[i], widths[i] = formatEntryName(file, flags)
+
+		// --git puts the status in front of the name
+		if badge := formatGitBadge(file, flags); badge != "" {
+			cells[i] = badge + cells[i]
+			widths[i] += 3
 		}
 
-		// Print main content row
-		for col := 0; col < numColumns; col++ {
-			idx := row + col*numRows
-			if col < len(mainContent) && mainContent[col] != "" {
-				colorCode := iconColors[col]
-				icon := icons[col]
-				name := mainContent[col]
-
-				// Special formatting for directories - make them bold and underlined and add "/"
-				if idx < len(files) && files[idx].IsDir() {
-					name += "/"
-				}
-
-				// File display with colored icon and white filename
-				fileDisplay := fmt.Sprintf("%s%s %s%s",
-					colorCode,
-					icon,
-					Color["white"]+name,
-					Color["reset"],
-				)
-
-				// Add padding for all columns except the last one
-				if col < numColumns-1 {
-					padding := columnWidths[col] - utf8.RuneCountInString(name) + 4
-					fmt.Print(fileDisplay + strings.Repeat(" ", padding))
-				} else {
-					fmt.Print(fileDisplay)
-				}
-			}
+		if prefixes != nil {
+			cells[i] = prefixes[i] + " " + cells[i]
+			widths[i] += visibleWidth(prefixes[i]) + 1
 		}
-		fmt.Print("\n")
-
-		// If there are long names, print overflow content
-		if hasLongName {
-			for col := 0; col < numColumns; col++ {
-				if col < len(overflowContent) && overflowContent[col] != "" {
-					colorCode := iconColors[col]
-					overflow := overflowContent[col]
-
-					// Preserve bold and underline for directories in overflow
-					idx := row + col*numRows
-					if idx < len(files) && files[idx].IsDir() {
-						overflow = "/" + overflow // Add "/" for directory overflow
-					}
-
-					// Truncate if still too long
-					if utf8.RuneCountInString(overflow) > maxFilenameWidth {
-						overflow = truncateString(overflow, maxFilenameWidth-3) + "..."
-					}
-
-					// Indented and colored continuation
-					overflowDisplay := fmt.Sprintf("%s  %s%s",
-						colorCode,
-						overflow,
-						Color["reset"],
-					)
-
-					// Add padding for all columns except the last one
-					if col < numColumns-1 {
-						padding := columnWidths[col] - utf8.RuneCountInString(overflow) + 4
-						fmt.Print(overflowDisplay + strings.Repeat(" ", padding))
-					} else {
-						fmt.Print(overflowDisplay)
-					}
-				} else if col < numColumns-1 {
-					// Empty continuation line, but maintain column width
-					fmt.Print(strings.Repeat(" ", columnWidths[col]+4))
-				}
-			}
-			fmt.Print("\n")
+	}
+
+	// -1 prints one entry per line
+	if flags.OnePerLine {
+		for _, cell := range cells {
+			fmt.Println(cell)
 		}
+		return
+	}
+
+	layout := computeGridLayout(widths, getLineWidth(flags), flags.Across)
+	printGrid(cells, widths, layout)
+}
+
+// getLineWidth returns the width to fit the grid into: -w if given,
+// otherwise the terminal's width
+func getLineWidth(flags Flags) int {
+	if flags.Width > 0 {
+		return flags.Width
 	}
+	return getTerminalWidth()
 }
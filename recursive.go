@@ -0,0 +1,71 @@
+// recursive.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// printDirectoryRecursive lists a directory and every subdirectory below it,
+// each under a "path:" header like ls -R
+func printDirectoryRecursive(dirPath string, flags Flags) {
+	walkDirectory(dirPath, flags, map[fileID]bool{}, true)
+}
+
+// walkDirectory prints one directory and recurses into its subdirectories.
+// active holds the directories currently being listed, so a directory that
+// leads back to one of its ancestors is reported instead of looping forever.
+func walkDirectory(dirPath string, flags Flags, active map[fileID]bool, first bool) {
+	// JSON entries carry their own paths, so headers are only for text output
+	if !flags.JSON {
+		if !first {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", dirPath)
+	}
+
+	if dirInfo, err := os.Stat(dirPath); err == nil {
+		if id, ok := getFileID(dirInfo); ok {
+			if active[id] {
+				reportError(exitTrouble, "%s: not listing already-listed directory", dirPath)
+				return
+			}
+			active[id] = true
+			defer delete(active, id)
+		}
+	}
+
+	fileInfos, err := readDirectory(dirPath, flags)
+	if err != nil {
+		// Only the directory named on the command line is serious trouble
+		status := exitMinor
+		if first {
+			status = exitTrouble
+		}
+		reportError(status, "cannot open directory '%s': %s", dirPath, describeError(err))
+		return
+	}
+
+	PrintFilesInColumns(filterEntries(fileInfos, flags), flags, true)
+
+	// Descend into every directory, even those --only-files didn't show
+	for _, info := range fileInfos {
+		// Only descend into real directories, symlinks are listed but not
+		// followed unless -L turned them into their targets
+		if !info.IsDir() || isDotDirectory(info) {
+			continue
+		}
+		walkDirectory(joinPath(dirPath, info.Name()), flags, active, false)
+	}
+}
+
+// joinPath joins a directory and an entry name the way ls displays them,
+// keeping a leading "./" instead of cleaning it away
+func joinPath(dir, name string) string {
+	if strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
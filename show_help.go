@@ -5,22 +5,122 @@ package main
 import "fmt"
 
 func showHelp() {
-	fmt.Println("Usage: myls [options] [path]")
+	fmt.Println("Usage: myls [options] [path...]")
 	fmt.Println("Options:")
-	fmt.Println("  -v, --version           Show version")
+	fmt.Println("      --version           Show version")
+	fmt.Println("      --config=FILE       Read settings from FILE instead of")
+	fmt.Println("                          $XDG_CONFIG_HOME/lsx/config.toml")
 	fmt.Println("  -h, --help              Show this help message")
-	fmt.Println("  [path]      	          Path to list (default: current directory)")
+	fmt.Println("      --interactive       Browse in a full-screen view and print the path picked")
+	fmt.Println("                          on exit, for cd \"$(lsx --interactive)\"")
+	fmt.Println("  -R, --recursive         List subdirectories recursively")
+	fmt.Println("      --total-size        Show directory sizes as the total of their contents")
+	fmt.Println("  -s, --size              Show the space allocated to each entry, in blocks")
+	fmt.Println("      --block-size=SIZE   Scale sizes by SIZE (K, M, G, 4K, 4096...); a bare")
+	fmt.Println("                          unit is printed after each size, e.g. --block-size=M")
+	fmt.Println("      --si                Like -h, but use powers of 1000, not 1024")
+	fmt.Println("  -i, --inode             Show the inode number of each entry")
+	fmt.Println("  -Z, --context           Show the SELinux security context of each entry")
+	fmt.Println("  -@                      List extended attributes and their sizes under")
+	fmt.Println("                          each entry in the long format")
+	fmt.Println("      --columns=LIST      Show these long format columns, in this order (implies -l):")
+	fmt.Println("                          perms, octal, links, owner, group, size, blocks,")
+	fmt.Println("                          time, git, inode, context, type, name")
+	fmt.Println("                          Permissions end in + for an ACL, @ for extended")
+	fmt.Println("                          attributes or . for just a SELinux context")
+	fmt.Println("  -F, --classify          Append indicator (one of /@*|=) to entries")
+	fmt.Println("  -L, --dereference       Show information for the targets of symlinks")
+	fmt.Println("  -H, --dereference-command-line")
+	fmt.Println("                          Follow symlinks given on the command line")
+	fmt.Println("      --git               Show each entry's git status (staged, then unstaged)")
+	fmt.Println("      --detect            Pick icons and colors from file contents (ELF, PE,")
+	fmt.Println("                          Mach-O, PNG, JPEG, PDF, gzip, zip, #! scripts)")
+	fmt.Println("      --gitignore         Hide entries ignored by .gitignore, .git/info/exclude")
+	fmt.Println("                          and the global excludes file")
+	fmt.Println("      --tree              Show directories as a tree")
+	fmt.Println("      --level=N           Limit the tree to N levels deep")
 	fmt.Println()
-	fmt.Println("Pattern matching:")
-	fmt.Println("  *.extension             List files with extension")
-	fmt.Println("  path/*.extension        List files with extension in path")
+	fmt.Println("Filtering:")
+	fmt.Println("  -a, --all               Show hidden entries, including . and ..")
+	fmt.Println("  -A, --almost-all        Show hidden entries, except . and ..")
+	fmt.Println("  -I, --ignore=PATTERN    Leave out entries matching PATTERN")
+	fmt.Println("      --hide=PATTERN      Leave out entries matching PATTERN, unless -a or -A")
+	fmt.Println("      --only-dirs         Show only directories")
+	fmt.Println("      --only-files        Show only files")
+	fmt.Println("      --larger-than=SIZE  Show only files larger than SIZE (e.g. 10M, 512K)")
+	fmt.Println("      --smaller-than=SIZE Show only files smaller than SIZE")
+	fmt.Println("      --newer-than=AGE    Show only files modified within AGE (e.g. 2d, 3h, 1w)")
+	fmt.Println("      --older-than=AGE    Show only files modified more than AGE ago")
+	fmt.Println()
+	fmt.Println("Layout:")
+	fmt.Println("  -C                      List entries in columns (default)")
+	fmt.Println("  -x                      List entries in rows instead of columns")
+	fmt.Println("  -1                      List one entry per line")
+	fmt.Println("  -w, --width=COLS        Set the output width instead of the terminal width")
+	fmt.Println("      --icon-width=N      Cells an icon takes in your terminal, 1 or 2 (default 1)")
+	fmt.Println()
+	fmt.Println("Colors and icons:")
+	fmt.Println("      --color[=WHEN]      Color the output: always, auto or never (default auto)")
+	fmt.Println("      --icons[=WHEN]      Show file icons: always, auto or never (default auto)")
+	fmt.Println("      --dircolors=FILE    Load colors from a dircolors database")
+	fmt.Println("                          (LS_COLORS is also honored)")
+	fmt.Println()
+	fmt.Println("Output:")
+	fmt.Println("      --json              Print entries as a JSON document")
+	fmt.Println("      --ndjson            Print entries as JSON, one object per line")
+	fmt.Println()
+	fmt.Println("Sorting:")
+	fmt.Println("  -S                      Sort by file size, largest first")
+	fmt.Println("  -t                      Sort by time (see --time), newest first")
+	fmt.Println("  -X                      Sort alphabetically by extension")
+	fmt.Println("  -v                      Natural sort of version numbers within names")
+	fmt.Println("  -U                      Do not sort; list entries in directory order")
+	fmt.Println("      --sort=WORD         Sort by WORD: name, none, size, time, extension, version")
+	fmt.Println("  -r, --reverse           Reverse the sort order")
+	fmt.Println("      --group-directories-first")
+	fmt.Println("                          List directories before files")
+	fmt.Println()
+	fmt.Println("Timestamps:")
+	fmt.Println("      --time=WORD         Show and sort by WORD: mtime (default), atime,")
+	fmt.Println("                          ctime or birth")
+	fmt.Println("  -c                      Use the status change time (--time=ctime)")
+	fmt.Println("  -u                      Use the access time (--time=atime)")
+	fmt.Println("      --time-style=STYLE  Show times as full-iso, long-iso, iso, relative")
+	fmt.Println("                          (\"3 minutes ago\") or +FORMAT, as for date(1)")
+	fmt.Println("  [path...]               Files and directories to list (default: current directory)")
+	fmt.Println()
+	fmt.Println("Pattern matching (quote patterns so the shell passes them on):")
+	fmt.Println("  *  ?  [abc]  [a-z]      Match any text, one character, or a character class")
+	fmt.Println("  {a,b}                   Match either alternative")
+	fmt.Println("  **                      Match any number of directories")
 	fmt.Println("  path/filename           Show details for filename")
 	fmt.Println()
+	fmt.Println("Exit status:")
+	fmt.Println("  0  if OK")
+	fmt.Println("  1  if minor problems (e.g., cannot access a subdirectory)")
+	fmt.Println("  2  if serious trouble (e.g., cannot access a command-line argument)")
+	fmt.Println()
+	fmt.Println("Interactive browser keys:")
+	fmt.Println("  j/k, arrows       Move; PgUp/PgDn, g/G for pages and ends")
+	fmt.Println("  l, right, enter   Enter a directory or archive; enter on a file picks it")
+	fmt.Println("  h, left           Go up to the parent directory")
+	fmt.Println("  /                 Fuzzy filter names; enter keeps it, esc clears it")
+	fmt.Println("  .  s  r  p        Toggle hidden files, cycle sort, reverse, toggle preview")
+	fmt.Println("  q                 Quit, picking the directory shown; Q or Ctrl-C cancels")
+	fmt.Println()
+	fmt.Println("Archives:")
+	fmt.Println("  tar (plain, .gz, .bz2, .xz), zip and jar files given as arguments are listed")
+	fmt.Println("  like directories, and so are paths inside them, e.g. src.tar.gz/src/cmd.")
+	fmt.Println("  Nothing is extracted. Use -d to list the file itself.")
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  myls")
 	fmt.Println("  myls /home/user/documents")
-	fmt.Println("  myls *.txt")
-	fmt.Println("  myls /var/log/*.log")
+	fmt.Println("  myls README.md src docs")
+	fmt.Println("  myls '*.txt'")
+	fmt.Println("  myls '/var/log/*.log'")
+	fmt.Println("  myls 'src/**/*.{go,mod}' '*.md'")
 	fmt.Println("  myls /etc/passwd")
+	fmt.Println("  myls -l release.tar.gz/release/bin")
 }
 
@@ -0,0 +1,143 @@
+// sorting.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sort orders selectable with the sort flags
+const (
+	SortName      = "name"
+	SortNone      = "none"
+	SortSize      = "size"
+	SortTime      = "time"
+	SortExtension = "extension"
+	SortVersion   = "version"
+)
+
+// sortKey is an entry together with the timestamp it sorts by, which is
+// looked up once per entry since access and birth times need a syscall
+type sortKey struct {
+	file os.FileInfo
+	time time.Time
+}
+
+// sortFiles orders a listing according to the sort flags
+func sortFiles(files []os.FileInfo, flags Flags) {
+	if flags.SortBy != SortNone {
+		keys := make([]sortKey, len(files))
+		for i, file := range files {
+			keys[i].file = file
+			if flags.SortBy == SortTime {
+				keys[i].time, _ = fileTime(file, flags.TimeField)
+			}
+		}
+
+		sort.SliceStable(keys, func(i, j int) bool {
+			return compareFiles(keys[i], keys[j], flags) < 0
+		})
+		for i, key := range keys {
+			files[i] = key.file
+		}
+	}
+
+	if flags.Reverse {
+		for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+			files[i], files[j] = files[j], files[i]
+		}
+	}
+
+	// Directories go first no matter the order, like ls does
+	if flags.GroupDirsFirst {
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].IsDir() && !files[j].IsDir()
+		})
+	}
+}
+
+// compareFiles compares two entries for the given sort order,
+// falling back to the name (or the path, for arguments) when they are equal
+func compareFiles(keyA, keyB sortKey, flags Flags) int {
+	a, b := keyA.file, keyB.file
+	switch flags.SortBy {
+	case SortSize:
+		// Largest first
+		if a.Size() != b.Size() {
+			if a.Size() > b.Size() {
+				return -1
+			}
+			return 1
+		}
+	case SortTime:
+		// Newest first, by the timestamp chosen with --time
+		if !keyA.time.Equal(keyB.time) {
+			if keyA.time.After(keyB.time) {
+				return -1
+			}
+			return 1
+		}
+	case SortExtension:
+		extA := strings.ToLower(filepath.Ext(a.Name()))
+		extB := strings.ToLower(filepath.Ext(b.Name()))
+		if extA != extB {
+			return strings.Compare(extA, extB)
+		}
+	case SortVersion:
+		return compareVersions(entryLabel(a), entryLabel(b))
+	}
+	return strings.Compare(entryLabel(a), entryLabel(b))
+}
+
+// compareVersions compares two names treating runs of digits as numbers,
+// so "file2" sorts before "file10"
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := splitDigits(a)
+			numB, restB := splitDigits(b)
+
+			// Compare numerically, ignoring leading zeros
+			trimA := strings.TrimLeft(numA, "0")
+			trimB := strings.TrimLeft(numB, "0")
+			if len(trimA) != len(trimB) {
+				if len(trimA) < len(trimB) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(trimA, trimB); c != 0 {
+				return c
+			}
+
+			a, b = restA, restB
+			continue
+		}
+
+		if a[0] != b[0] {
+			if a[0] < b[0] {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// splits a string into its leading run of digits and the rest
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
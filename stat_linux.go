@@ -0,0 +1,127 @@
+// stat_linux.go
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Caches for uid/gid to name lookups, so every row of a long listing
+// doesn't hit /etc/passwd and /etc/group again
+var (
+	userNameCache  = map[uint32]string{}
+	groupNameCache = map[uint32]string{}
+)
+
+// getStat returns the raw stat data behind an os.FileInfo, if available
+func getStat(file os.FileInfo) (*syscall.Stat_t, bool) {
+	stat, ok := file.Sys().(*syscall.Stat_t)
+	return stat, ok
+}
+
+// getFileOwner returns the owner and group names of a file
+func getFileOwner(file os.FileInfo) (string, string) {
+	if owner, group, ok := archiveOwner(file); ok {
+		return owner, group
+	}
+	stat, ok := getStat(file)
+	if !ok {
+		username := getCurrentUsername()
+		return username, username
+	}
+	return lookupUserName(stat.Uid), lookupGroupName(stat.Gid)
+}
+
+// getLinkCount gets the number of hard links to a file
+func getLinkCount(file os.FileInfo) uint64 {
+	stat, ok := getStat(file)
+	if !ok {
+		return 1
+	}
+	return uint64(stat.Nlink)
+}
+
+// resolves a uid to a user name, falling back to the numeric id like ls does
+func lookupUserName(uid uint32) string {
+	if name, ok := userNameCache[uid]; ok {
+		return name
+	}
+
+	id := strconv.FormatUint(uint64(uid), 10)
+	name := id
+	if u, err := user.LookupId(id); err == nil {
+		name = u.Username
+	}
+	userNameCache[uid] = name
+	return name
+}
+
+// resolves a gid to a group name, falling back to the numeric id like ls does
+func lookupGroupName(gid uint32) string {
+	if name, ok := groupNameCache[gid]; ok {
+		return name
+	}
+
+	id := strconv.FormatUint(uint64(gid), 10)
+	name := id
+	if g, err := user.LookupGroupId(id); err == nil {
+		name = g.Name
+	}
+	groupNameCache[gid] = name
+	return name
+}
+
+// fileID identifies a file by device and inode
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID returns the device and inode pair of a file
+func getFileID(file os.FileInfo) (fileID, bool) {
+	stat, ok := getStat(file)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
+
+// getFileTimes returns the access and status change times of a file
+func getFileTimes(file os.FileInfo) (time.Time, time.Time, bool) {
+	stat, ok := getStat(file)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	atime := time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	ctime := time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+	return atime, ctime, true
+}
+
+// getDeviceNumbers returns the major and minor numbers of a device file
+func getDeviceNumbers(file os.FileInfo) (uint32, uint32, bool) {
+	stat, ok := getStat(file)
+	if !ok || file.Mode()&os.ModeDevice == 0 {
+		return 0, 0, false
+	}
+	rdev := uint64(stat.Rdev)
+	return unix.Major(rdev), unix.Minor(rdev), true
+}
+
+// getAllocatedSize returns the bytes a file takes on disk, from its 512-byte block
+// count. Entries inside an archive take no space of their own.
+func getAllocatedSize(file os.FileInfo) int64 {
+	if _, ok := file.Sys().(*archiveFileInfo); ok {
+		return 0
+	}
+	stat, ok := getStat(file)
+	if !ok {
+		return file.Size()
+	}
+	return int64(stat.Blocks) * 512
+}
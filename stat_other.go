@@ -0,0 +1,64 @@
+//go:build !linux
+
+// stat_other.go
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// getFileOwner returns the owner and group of a file
+func getFileOwner(file os.FileInfo) (string, string) {
+	if owner, group, ok := archiveOwner(file); ok {
+		return owner, group
+	}
+	// In Windows, for simplicity, just use the current user as owner & group.
+	username := getCurrentUsername()
+	return username, username
+}
+
+// gets the number of hard links (simplified for Windows)
+func getLinkCount(file os.FileInfo) uint64 {
+	// On Windows, this concept doesn't directly map the same as in Unix
+	if file.IsDir() {
+		return 2
+	}
+	return 1
+}
+
+// fileID identifies a file by device and inode
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID is not available without Unix stat data
+func getFileID(file os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+// getFileTimes is not available without Unix stat data
+func getFileTimes(file os.FileInfo) (time.Time, time.Time, bool) {
+	return time.Time{}, time.Time{}, false
+}
+
+// getDeviceNumbers is not available without Unix stat data
+func getDeviceNumbers(file os.FileInfo) (uint32, uint32, bool) {
+	return 0, 0, false
+}
+
+// getAllocatedSize falls back to the apparent size without Unix stat data.
+// Entries inside an archive take no space of their own.
+func getAllocatedSize(file os.FileInfo) int64 {
+	if _, ok := file.Sys().(*archiveFileInfo); ok {
+		return 0
+	}
+	return file.Size()
+}
+
+// getBirthTime is only read through statx on Linux
+func getBirthTime(file os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,74 @@
+// symlinks.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// formatLinkTarget renders " -> target" for a symlink in long format, with
+// the target colored by its own type. Broken targets are highlighted.
+func formatLinkTarget(file os.FileInfo, flags Flags) string {
+	if file.Mode()&os.ModeSymlink == 0 {
+		return ""
+	}
+
+	path := entryPath(file)
+	target, err := readLinkPath(path)
+	if err != nil {
+		return ""
+	}
+
+	// Relative targets are relative to the directory holding the link
+	targetPath := target
+	if !filepath.IsAbs(target) {
+		targetPath = filepath.Join(filepath.Dir(path), target)
+	}
+
+	targetInfo, err := statPath(targetPath)
+	if err != nil {
+		colorCode := resolveColor("bright_red")
+		if color, ok := lsColors.firstOf("mi", "or"); ok {
+			colorCode = color
+		}
+		if !colorsEnabled {
+			colorCode = ""
+		}
+		return " -> " + colorCode + target + Color["reset"]
+	}
+
+	// Show the target as written in the link, but style it by its type
+	display, _ := formatEntryName(&fileEntry{FileInfo: targetInfo, path: targetPath, label: target}, flags)
+	return " -> " + display
+}
+
+// classifyIndicator returns the character -F appends for a file type
+func classifyIndicator(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "" // directories always get "/"
+	case mode&os.ModeSymlink != 0:
+		return "@"
+	case mode&os.ModeNamedPipe != 0:
+		return "|"
+	case mode&os.ModeSocket != 0:
+		return "="
+	case mode.IsRegular() && mode&0111 != 0:
+		return "*"
+	default:
+		return ""
+	}
+}
+
+// followLink returns the info of a symlink's target when -L is given,
+// keeping the link's own info if the target is missing
+func followLink(info os.FileInfo, path string, flags Flags) os.FileInfo {
+	if !flags.Dereference || info.Mode()&os.ModeSymlink == 0 {
+		return info
+	}
+	if target, err := statPath(path); err == nil {
+		return target
+	}
+	return info
+}
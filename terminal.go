@@ -0,0 +1,87 @@
+// terminal.go
+
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Width used when the terminal size can't be determined
+const defaultTerminalWidth = 80
+
+// reads the line width from $COLUMNS
+func getColumnsFromEnv() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return defaultTerminalWidth
+}
+
+// Values of --color and --icons
+const (
+	ModeAuto   = "auto"
+	ModeAlways = "always"
+	ModeNever  = "never"
+)
+
+// Whether entries are drawn with colors and icons, decided by setupOutputStyle
+var (
+	colorsEnabled = true
+	iconsEnabled  = true
+)
+
+// setupOutputStyle decides on colors, icons and the default layout from
+// the flags, the environment and whether stdout is a terminal
+func setupOutputStyle(flags *Flags) {
+	// The interactive browser draws on the terminal even when stdout
+	// is captured for its result
+	tty := isTerminal() || flags.Interactive
+	dumb := os.Getenv("TERM") == "dumb"
+
+	switch flags.ColorMode {
+	case ModeAlways:
+		colorsEnabled = true
+	case ModeNever:
+		colorsEnabled = false
+	default:
+		// A non-empty NO_COLOR disables colors, see no-color.org
+		noColor := os.Getenv("NO_COLOR") != ""
+		colorsEnabled = tty && !dumb && !noColor
+	}
+
+	switch flags.IconsMode {
+	case ModeAlways:
+		iconsEnabled = true
+	case ModeNever:
+		iconsEnabled = false
+	default:
+		iconsEnabled = tty && !dumb
+	}
+
+	// Without colors every escape sequence becomes empty
+	if !colorsEnabled {
+		for name := range Color {
+			Color[name] = ""
+		}
+	}
+
+	// Like ls, list one entry per line when output isn't a terminal
+	if !tty && !flags.ForceColumns && !flags.Across {
+		flags.OnePerLine = true
+	}
+}
+
+// parseWhenValue checks the value of --color or --icons
+func parseWhenValue(name, value string) string {
+	switch value {
+	case "", "always", "yes", "force":
+		return ModeAlways
+	case "never", "no", "none":
+		return ModeNever
+	case "auto", "tty", "if-tty":
+		return ModeAuto
+	}
+	flagError("invalid argument '%s' for '%s'\nValid arguments are: 'always', 'auto', 'never'", value, name)
+	return ""
+}
@@ -0,0 +1,14 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+// terminal_bsd.go
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// ioctl requests to read and change terminal settings, which x/sys
+// names differently on each system
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
@@ -0,0 +1,12 @@
+// terminal_linux.go
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// ioctl requests to read and change terminal settings, which x/sys
+// names differently on each system
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
@@ -0,0 +1,34 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+// terminal_other.go
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// getTerminalWidth uses $COLUMNS, or 80 columns when it isn't set
+func getTerminalWidth() int {
+	return getColumnsFromEnv()
+}
+
+// isTerminal reports whether stdout is a character device like a console
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// getWindowSize has no portable way to ask the console for its size
+func getWindowSize(fd uintptr) (int, int, bool) {
+	return 0, 0, false
+}
+
+// makeRaw is only implemented for Unix terminals
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("interactive mode needs a Unix terminal")
+}
+
+// notifyResize does nothing without SIGWINCH
+func notifyResize(ch chan os.Signal) {}
@@ -0,0 +1,68 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+// terminal_unix.go
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// getWindowSize asks a tty for its size in rows and columns
+func getWindowSize(fd uintptr) (int, int, bool) {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(ws.Row), int(ws.Col), true
+}
+
+// getTerminalWidth asks the tty on stdout for its width, falling back
+// to $COLUMNS and then to 80 columns
+func getTerminalWidth() int {
+	if _, cols, ok := getWindowSize(os.Stdout.Fd()); ok && cols > 0 {
+		return cols
+	}
+	return getColumnsFromEnv()
+}
+
+// isTerminal reports whether stdout is a terminal, using the same
+// ioctl that only succeeds on a tty
+func isTerminal() bool {
+	_, _, ok := getWindowSize(os.Stdout.Fd())
+	return ok
+}
+
+// makeRaw puts a tty into raw mode, so every key press arrives as it is
+// typed and isn't echoed, and returns a function that restores it.
+// Output processing stays on, so "\n" still starts a new line.
+func makeRaw(fd uintptr) (func(), error) {
+	saved, err := unix.IoctlGetTermios(int(fd), ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *saved
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(int(fd), ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		unix.IoctlSetTermios(int(fd), ioctlSetTermios, saved)
+	}, nil
+}
+
+// notifyResize sends on the channel whenever the terminal changes size
+func notifyResize(ch chan os.Signal) {
+	signal.Notify(ch, unix.SIGWINCH)
+}
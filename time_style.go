@@ -0,0 +1,245 @@
+// time_style.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamps selectable with --time, -c and -u
+const (
+	TimeModified = "mtime"
+	TimeAccess   = "atime"
+	TimeChange   = "ctime"
+	TimeBirth    = "birth"
+)
+
+// Styles for --time-style, besides "+FORMAT"
+const (
+	StyleDefault  = ""
+	StyleFullISO  = "full-iso"
+	StyleLongISO  = "long-iso"
+	StyleISO      = "iso"
+	StyleRelative = "relative"
+)
+
+// parseTimeField accepts the --time words ls does
+func parseTimeField(value string) (string, bool) {
+	switch value {
+	case "mtime", "modification":
+		return TimeModified, true
+	case "atime", "access", "use":
+		return TimeAccess, true
+	case "ctime", "status":
+		return TimeChange, true
+	case "birth", "btime", "creation":
+		return TimeBirth, true
+	}
+	return "", false
+}
+
+// validTimeStyle reports whether a --time-style value is known
+func validTimeStyle(style string) bool {
+	switch style {
+	case StyleFullISO, StyleLongISO, StyleISO, StyleRelative:
+		return true
+	}
+	return strings.HasPrefix(style, "+")
+}
+
+// fileTime returns the selected timestamp of an entry. Access, change and
+// birth times need stat data, so ok is false where it is missing.
+func fileTime(file os.FileInfo, field string) (time.Time, bool) {
+	switch field {
+	case TimeAccess, TimeChange:
+		atime, ctime, ok := getFileTimes(file)
+		if field == TimeAccess {
+			return atime, ok
+		}
+		return ctime, ok
+	case TimeBirth:
+		return getBirthTime(file)
+	}
+	return file.ModTime(), true
+}
+
+// formatFileTime formats the selected timestamp of an entry, or "-" when
+// the file doesn't have it (like a birth time on most network filesystems)
+func formatFileTime(file os.FileInfo, flags Flags) string {
+	t, ok := fileTime(file, flags.TimeField)
+	if !ok {
+		return "-"
+	}
+	return FormatTime(t, flags.TimeStyle)
+}
+
+// FormatTime formats a timestamp in the given --time-style
+func FormatTime(t time.Time, style string) string {
+	now := time.Now()
+
+	// Like ls, times from the last six months show the time of day, older
+	// ones and ones in the future show the year
+	recent := t.After(now.AddDate(0, -6, 0)) && !t.After(now)
+
+	switch style {
+	case StyleFullISO:
+		return t.Format("2006-01-02 15:04:05.000000000 -0700")
+	case StyleLongISO:
+		return t.Format("2006-01-02 15:04")
+	case StyleISO:
+		if recent {
+			return t.Format("01-02 15:04")
+		}
+		return t.Format("2006-01-02 ")
+	case StyleRelative:
+		return formatRelativeTime(t, now)
+	}
+
+	if strings.HasPrefix(style, "+") {
+		// "+OLD\nRECENT" gives a separate format for recent times
+		format := style[1:]
+		if older, newer, found := strings.Cut(format, "\n"); found {
+			format = older
+			if recent {
+				format = newer
+			}
+		}
+		return formatStrftime(t, format)
+	}
+
+	if recent {
+		return t.Format("Jan _2 15:04")
+	}
+	return t.Format("Jan _2  2006")
+}
+
+// Steps for relative times, each used until the next one fits
+var relativeUnits = []struct {
+	size time.Duration
+	name string
+}{
+	{time.Second, "second"},
+	{time.Minute, "minute"},
+	{time.Hour, "hour"},
+	{24 * time.Hour, "day"},
+	{7 * 24 * time.Hour, "week"},
+	{30 * 24 * time.Hour, "month"},
+	{365 * 24 * time.Hour, "year"},
+}
+
+// formatRelativeTime describes a time like "3 minutes ago" or "in 2 days"
+func formatRelativeTime(t, now time.Time) string {
+	diff := now.Sub(t)
+	future := diff < 0
+	if future {
+		diff = -diff
+	}
+	if diff < time.Second {
+		return "just now"
+	}
+
+	unit := relativeUnits[0]
+	for _, next := range relativeUnits[1:] {
+		if diff < next.size {
+			break
+		}
+		unit = next
+	}
+
+	count := int(diff / unit.size)
+	text := fmt.Sprintf("%d %s", count, pluralize(count, unit.name, unit.name+"s"))
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+// formatStrftime formats a time with the strftime directives date(1) knows,
+// as used by --time-style=+FORMAT. Unknown directives are kept as they are.
+func formatStrftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		switch format[i] {
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'b', 'h':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'c':
+			b.WriteString(t.Format("Mon Jan _2 15:04:05 2006"))
+		case 'C':
+			fmt.Fprintf(&b, "%02d", t.Year()/100)
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'D':
+			b.WriteString(t.Format("01/02/06"))
+		case 'e':
+			b.WriteString(t.Format("_2"))
+		case 'F':
+			b.WriteString(t.Format("2006-01-02"))
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'I':
+			b.WriteString(t.Format("03"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'k':
+			fmt.Fprintf(&b, "%2d", t.Hour())
+		case 'l':
+			fmt.Fprintf(&b, "%2d", (t.Hour()+11)%12+1)
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case 'n':
+			b.WriteByte('\n')
+		case 'N':
+			fmt.Fprintf(&b, "%09d", t.Nanosecond())
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'P':
+			b.WriteString(t.Format("pm"))
+		case 'R':
+			b.WriteString(t.Format("15:04"))
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			b.WriteString(t.Format("05"))
+		case 't':
+			b.WriteByte('\t')
+		case 'T':
+			b.WriteString(t.Format("15:04:05"))
+		case 'u':
+			fmt.Fprintf(&b, "%d", (int(t.Weekday())+6)%7+1)
+		case 'w':
+			fmt.Fprintf(&b, "%d", int(t.Weekday()))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'Y':
+			fmt.Fprintf(&b, "%d", t.Year())
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
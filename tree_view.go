@@ -0,0 +1,106 @@
+// tree_view.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Branch pieces used to draw the tree
+const (
+	treeBranch     = "├── "
+	treeLastBranch = "└── "
+	treeVertical   = "│   "
+	treeSpace      = "    "
+)
+
+// counts of what the tree printed, for the summary line
+type treeCounts struct {
+	dirs  int
+	files int
+}
+
+// printTree draws a directory hierarchy like the tree command, with
+// the same icons and colors as the column view
+func printTree(dirPath string, flags Flags) {
+	// The tree always lists directories before files
+	flags.GroupDirsFirst = true
+
+	fmt.Printf("%s%s%s\n", Color["blue"], dirPath, Color["reset"])
+
+	counts := &treeCounts{}
+	printTreeLevel(dirPath, "", 1, flags, counts, map[fileID]bool{})
+
+	fmt.Printf("\n%d %s, %d %s\n",
+		counts.dirs, pluralize(counts.dirs, "directory", "directories"),
+		counts.files, pluralize(counts.files, "file", "files"),
+	)
+}
+
+// prints the entries of one directory below the given branch prefix.
+// active holds the directories on the current branch, so links followed
+// with -L can't send the tree around in circles.
+func printTreeLevel(dirPath string, prefix string, depth int, flags Flags, counts *treeCounts, active map[fileID]bool) {
+	if dirInfo, err := os.Stat(dirPath); err == nil {
+		if id, ok := getFileID(dirInfo); ok {
+			if active[id] {
+				reportError(exitTrouble, "%s: not listing already-listed directory", dirPath)
+				return
+			}
+			active[id] = true
+			defer delete(active, id)
+		}
+	}
+
+	fileInfos, err := readDirectory(dirPath, flags)
+	if err != nil {
+		// Only the directory named on the command line is serious trouble
+		status := exitMinor
+		if depth == 1 {
+			status = exitTrouble
+		}
+		reportError(status, "cannot open directory '%s': %s", dirPath, describeError(err))
+		return
+	}
+
+	// Directories stay in the tree to hold its shape, even with --only-files
+	shown := make([]os.FileInfo, 0, len(fileInfos))
+	for _, file := range fileInfos {
+		if file.IsDir() || passesFilters(file, flags) {
+			shown = append(shown, file)
+		}
+	}
+
+	for i, file := range shown {
+		isLast := i == len(shown)-1
+
+		branch, childPrefix := treeBranch, prefix+treeVertical
+		if isLast {
+			branch, childPrefix = treeLastBranch, prefix+treeSpace
+		}
+
+		name, _ := formatEntryName(file, flags)
+		fmt.Printf("%s%s%s%s\n", prefix, branch, formatGitBadge(file, flags), name)
+
+		if !file.IsDir() {
+			counts.files++
+			continue
+		}
+		counts.dirs++
+
+		// Stop descending once the --level limit is reached
+		if flags.TreeLevel > 0 && depth >= flags.TreeLevel {
+			continue
+		}
+		printTreeLevel(joinPath(dirPath, file.Name()), childPrefix, depth+1, flags, counts, active)
+	}
+}
+
+// pluralize picks the singular or plural form of a word for a count
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
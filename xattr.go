@@ -0,0 +1,55 @@
+// xattr.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Extended attributes with a meaning of their own
+const (
+	xattrACLAccess  = "system.posix_acl_access"
+	xattrACLDefault = "system.posix_acl_default"
+	xattrSELinux    = "security.selinux"
+)
+
+// accessMarker returns the character ls puts after the permissions: "+"
+// for an ACL, "@" for other extended attributes and "." for a SELinux
+// context alone, or "" for none of them
+func accessMarker(file os.FileInfo) string {
+	names := listXattrs(file)
+	marker := ""
+	for _, name := range names {
+		switch name {
+		case xattrACLAccess, xattrACLDefault:
+			return "+"
+		case xattrSELinux:
+			if marker == "" {
+				marker = "."
+			}
+		default:
+			marker = "@"
+		}
+	}
+	return marker
+}
+
+// securityContext returns the SELinux label of an entry for -Z, "?" if it has none
+func securityContext(file os.FileInfo) string {
+	value, ok := getXattr(file, xattrSELinux)
+	if !ok {
+		return "?"
+	}
+	return strings.TrimRight(string(value), "\x00")
+}
+
+// printXattrs lists the extended attributes of an entry under its line
+// for -@, with the size of each value
+func printXattrs(file os.FileInfo) {
+	for _, name := range listXattrs(file) {
+		value, _ := getXattr(file, name)
+		fmt.Printf("\t%s\t%4d\n", name, len(value))
+	}
+}
@@ -0,0 +1,64 @@
+// xattr_linux.go
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the names of the extended attributes of an entry,
+// those of a symlink itself rather than its target
+func listXattrs(file os.FileInfo) []string {
+	list := unix.Listxattr
+	if file.Mode()&os.ModeSymlink != 0 {
+		list = unix.Llistxattr
+	}
+
+	buf, ok := readXattr(entryPath(file), list)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// getXattr returns the value of one extended attribute of an entry
+func getXattr(file os.FileInfo, name string) ([]byte, bool) {
+	get := unix.Getxattr
+	if file.Mode()&os.ModeSymlink != 0 {
+		get = unix.Lgetxattr
+	}
+
+	return readXattr(entryPath(file), func(path string, buf []byte) (int, error) {
+		return get(path, name, buf)
+	})
+}
+
+// readXattr asks for the size of a list or value and then reads it,
+// growing the buffer if the attributes change in between
+func readXattr(path string, read func(path string, buf []byte) (int, error)) ([]byte, bool) {
+	for {
+		size, err := read(path, nil)
+		if err != nil {
+			return nil, false
+		}
+		buf := make([]byte, size)
+		n, err := read(path, buf)
+		if err == unix.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, false
+		}
+		return buf[:n], true
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+// xattr_other.go
+
+package main
+
+import "os"
+
+// listXattrs is only implemented on Linux
+func listXattrs(file os.FileInfo) []string {
+	return nil
+}
+
+// getXattr is only implemented on Linux
+func getXattr(file os.FileInfo, name string) ([]byte, bool) {
+	return nil, false
+}